@@ -0,0 +1,212 @@
+package grc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adminKeysLimit is the default number of keys listed by the admin handler
+const adminKeysLimit = 1000
+
+// EntryInfo is a struct describing a cache entry
+type EntryInfo struct {
+	Key        string
+	Size       int           // size of the stored value in bytes
+	TTL        time.Duration // remaining ttl, 0 without expiration or if the cache client can not tell
+	FreshUntil time.Time     // time until which the entry is fresh with SoftTTL or StaleTTL, zero otherwise
+	Value      interface{}   // decoded value, nil for empty results or if it can not be decoded
+	Error      string        // reason the value can not be decoded
+}
+
+// Inspect gets the entry of key and decodes its value with the codec and stages of the config,
+// returns ErrCacheMiss if the key does not exist
+func (g *GormCache) Inspect(ctx context.Context, key string) (EntryInfo, error) {
+	info := EntryInfo{Key: key}
+	value, ttl, err := getWithTTL(ctx, g.Client(), key)
+	info.TTL = ttl
+	if isCacheMiss(err) || (err == nil && value == nil) {
+		return info, ErrCacheMiss
+	}
+	if err != nil {
+		return info, err
+	}
+	data, err := ValueBytes(value)
+	if err != nil {
+		return info, err
+	}
+	info.Size = len(data)
+
+	data, info.FreshUntil, _ = entryHeader(data)
+	if len(data) > 0 {
+		if info.Value, err = decodeValue(g.Config(), key, data); err != nil {
+			info.Error = err.Error()
+		}
+	}
+	return info, nil
+}
+
+// decodeValue reverses the stages of config on the data of key and deserializes it into a generic value, without statistics
+func decodeValue(config CacheConfig, key string, data []byte) (interface{}, error) {
+	for i := len(config.Stages) - 1; i >= 0; i-- {
+		out, err := decodeStage(config.Stages[i], key, data)
+		if err != nil {
+			return nil, err
+		}
+		data = out
+	}
+	var value interface{}
+	if err := codecOf(config).Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// AdminHandler returns an http.Handler with endpoints to manage the cache, mount it under a base path
+// with http.StripPrefix and protect it like any other admin endpoint:
+//
+//	GET    /stats                       statistics of the cache
+//	GET    /keys?prefix=p&limit=n       keys under a prefix, at most 1000 by default
+//	DELETE /keys?prefix=p               deletes all keys under a prefix
+//	GET    /entries/{key}               ttl, size and decoded value of an entry
+//	DELETE /entries/{key}               deletes an entry
+//	DELETE /tables/{table}              invalidates the entries of a table, also on other instances with a Broadcaster
+//
+// Keys and prefixes are full keys including the configured Prefix, responses are json.
+func AdminHandler(cache *GormCache) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			adminMethodNotAllowed(w, "GET")
+			return
+		}
+		stats := cache.Stats()
+		writeJSON(w, statsJSON{Stats: stats, HitRatio: stats.HitRatio()})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		prefix := r.URL.Query().Get("prefix")
+		switch r.Method {
+		case http.MethodGet:
+			limit := adminKeysLimit
+			if s := r.URL.Query().Get("limit"); s != "" {
+				var err error
+				if limit, err = strconv.Atoi(s); err != nil {
+					http.Error(w, "invalid limit", http.StatusBadRequest)
+					return
+				}
+			}
+			lister, ok := cache.Client().(KeyLister)
+			if !ok {
+				http.Error(w, ErrNotSupported.Error(), http.StatusNotImplemented)
+				return
+			}
+			keys, err := lister.ListKeys(r.Context(), prefix, limit)
+			if errors.Is(err, ErrNotSupported) {
+				http.Error(w, err.Error(), http.StatusNotImplemented)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			if keys == nil {
+				keys = []string{}
+			}
+			writeJSON(w, keys)
+		case http.MethodDelete:
+			if prefix == "" {
+				http.Error(w, "missing prefix", http.StatusBadRequest) // flushing everything takes a deliberate prefix
+				return
+			}
+			flusher, ok := cache.Client().(PrefixFlusher)
+			if !ok {
+				http.Error(w, ErrNotSupported.Error(), http.StatusNotImplemented)
+				return
+			}
+			err := flusher.FlushPrefix(r.Context(), prefix)
+			if errors.Is(err, ErrNotSupported) {
+				http.Error(w, err.Error(), http.StatusNotImplemented)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			adminMethodNotAllowed(w, "GET, DELETE")
+		}
+	})
+	mux.HandleFunc("/entries/", func(w http.ResponseWriter, r *http.Request) {
+		key, err := pathParam(r, "/entries/")
+		if err != nil || key == "" {
+			http.Error(w, "invalid key", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			info, err := cache.Inspect(r.Context(), key)
+			if isCacheMiss(err) {
+				http.NotFound(w, r)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, info)
+		case http.MethodDelete:
+			if err := cache.InvalidateKey(r.Context(), key); err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			adminMethodNotAllowed(w, "GET, DELETE")
+		}
+	})
+	mux.HandleFunc("/tables/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			adminMethodNotAllowed(w, "DELETE")
+			return
+		}
+		table, err := pathParam(r, "/tables/")
+		if err != nil {
+			http.Error(w, "invalid table", http.StatusBadRequest)
+			return
+		}
+		if table == "" {
+			http.Error(w, "missing table", http.StatusBadRequest)
+			return
+		}
+		if err := cache.InvalidateTable(r.Context(), table); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// pathParam returns the unescaped path of r after prefix, escaped slashes are part of the parameter
+func pathParam(r *http.Request, prefix string) (string, error) {
+	return url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), prefix))
+}
+
+func adminMethodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+// writeJSON writes v as a json response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,107 @@
+package grc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAdminHandler tests listing, inspecting and deleting entries with the admin endpoints
+func TestAdminHandler(t *testing.T) {
+	ctx := context.Background()
+	client := NewMemoryCache()
+	cache := NewGormCache("admin_cache", client, CacheConfig{TTL: time.Minute})
+	data, err := json.Marshal(map[string]interface{}{"ID": 1, "Name": "a"})
+	assert.NoError(t, err)
+	assert.NoError(t, client.Set(ctx, "admin:a", data, time.Minute))
+	assert.NoError(t, client.Set(ctx, "admin:b", []byte(`[]`), time.Minute))
+	assert.NoError(t, client.Set(ctx, "other:c", []byte(`[]`), time.Minute))
+
+	server := httptest.NewServer(http.StripPrefix("/cache", AdminHandler(cache)))
+	defer server.Close()
+	do := func(method, path string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+"/cache"+path, nil)
+		assert.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	resp := do(http.MethodGet, "/stats")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = do(http.MethodGet, "/keys?prefix=admin:")
+	var keys []string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+	resp.Body.Close()
+	assert.ElementsMatch(t, []string{"admin:a", "admin:b"}, keys)
+
+	resp = do(http.MethodGet, "/entries/admin:a")
+	var info EntryInfo
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	resp.Body.Close()
+	assert.Equal(t, "admin:a", info.Key)
+	assert.Equal(t, len(data), info.Size)
+	assert.Greater(t, info.TTL, time.Duration(0))
+	assert.Equal(t, map[string]interface{}{"ID": 1.0, "Name": "a"}, info.Value)
+
+	resp = do(http.MethodGet, "/entries/admin:x")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = do(http.MethodDelete, "/entries/admin:a")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, []string{"admin:b", "other:c"}, client.Keys(""))
+
+	resp = do(http.MethodDelete, "/keys")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = do(http.MethodDelete, "/keys?prefix=admin:")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, []string{"other:c"}, client.Keys(""))
+
+	resp = do(http.MethodPost, "/stats")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// escaped slashes are part of keys and table names alike
+	assert.NoError(t, client.Set(ctx, "a/b", []byte(`[]`), time.Minute))
+	assert.NoError(t, client.Set(ctx, "my/table:x", []byte(`[]`), time.Minute))
+	assert.NoError(t, client.Set(ctx, "table:y", []byte(`[]`), time.Minute))
+	resp = do(http.MethodDelete, "/entries/a%2Fb")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do(http.MethodDelete, "/tables/my%2Ftable")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, []string{"other:c", "table:y"}, client.Keys(""))
+	resp = do(http.MethodDelete, "/tables/")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestInspectStale tests inspecting entries with a freshness header
+func TestInspectStale(t *testing.T) {
+	client := newMapClient()
+	cache := NewGormCache("inspect_cache", client, CacheConfig{TTL: time.Minute})
+	fresh := time.Now().Add(time.Minute)
+	client.values["k"] = wrapEntry([]byte(`[{"ID":2}]`), fresh)
+
+	info, err := cache.Inspect(context.Background(), "k")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), info.TTL) // unknown without GetWithTTL
+	assert.WithinDuration(t, fresh, info.FreshUntil, time.Millisecond)
+	assert.Equal(t, []interface{}{map[string]interface{}{"ID": 2.0}}, info.Value)
+
+	_, err = cache.Inspect(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
@@ -0,0 +1,114 @@
+package grc
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// auditSweepInterval is the number of recorded writes between removals of expired audit entries
+const auditSweepInterval = 1024
+
+// AuditStats is a struct for the would-be statistics of the audit mode
+type AuditStats struct {
+	Hits    int64 // number of queries which would have been served from cache
+	Misses  int64 // number of queries which would not have been found in cache
+	Sets    int64 // number of entries which would have been written to cache
+	Entries int64 // number of unexpired entries which would be in the cache
+	Bytes   int64 // total size of the unexpired entries, an estimate of the memory footprint in the backend
+}
+
+// HitRatio returns the ratio of would-be hits to hits and misses, 0 if there were none
+func (s AuditStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// auditEntry is a would-be cache entry of the audit mode
+type auditEntry struct {
+	size     int
+	expireAt time.Time // zero without expiration
+}
+
+// audit simulates the cache in audit mode with the keys, sizes and expiration of would-be entries
+type audit struct {
+	mu      sync.Mutex
+	entries map[string]auditEntry
+	hits    int64
+	misses  int64
+	sets    int64
+}
+
+// lookup records a would-be hit or miss of key and reports whether it is a hit
+func (a *audit) lookup(key string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.entries[key]
+	if ok && !entry.expireAt.IsZero() && !now.Before(entry.expireAt) {
+		delete(a.entries, key)
+		ok = false
+	}
+	if ok {
+		a.hits++
+	} else {
+		a.misses++
+	}
+	return ok
+}
+
+// set records a would-be write of key
+func (a *audit) set(key string, size int, ttl time.Duration, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.entries == nil {
+		a.entries = make(map[string]auditEntry)
+	}
+	entry := auditEntry{size: size}
+	if ttl > 0 {
+		entry.expireAt = now.Add(ttl)
+	}
+	a.entries[key] = entry
+	if a.sets++; a.sets%auditSweepInterval == 0 {
+		a.sweep(now)
+	}
+}
+
+// sweep removes expired entries, a.mu must be held
+func (a *audit) sweep(now time.Time) {
+	for key, entry := range a.entries {
+		if !entry.expireAt.IsZero() && !now.Before(entry.expireAt) {
+			delete(a.entries, key)
+		}
+	}
+}
+
+func (a *audit) snapshot(now time.Time) AuditStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sweep(now)
+	stats := AuditStats{Hits: a.hits, Misses: a.misses, Sets: a.sets, Entries: int64(len(a.entries))}
+	for _, entry := range a.entries {
+		stats.Bytes += int64(entry.size)
+	}
+	return stats
+}
+
+// AuditStats returns the would-be statistics of the audit mode
+func (g *GormCache) AuditStats() AuditStats {
+	return g.audit.snapshot(time.Now())
+}
+
+// auditQuery records a would-be hit or miss of the query of db, which is always sent to the database,
+// and a would-be write of its result on a miss
+func (g *GormCache) auditQuery(db *gorm.DB, key string, config CacheConfig) {
+	hit := !refreshCache(db) && g.audit.lookup(key, time.Now())
+	g.queryDB(db)
+	if !hit && cacheable(db, config) {
+		if err := g.setCache(db, key, config); err != nil {
+			logMessage(db.Statement.Context, config, LogWarn, "audit cache failed", err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package grc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAudit tests recording would-be hits, misses and entries without using the cache client
+func TestAudit(t *testing.T) {
+	client := newMapClient()
+	cache := NewGormCache("audit_cache", client, CacheConfig{TTL: time.Minute, Audit: true})
+	assert.NoError(t, db.Use(cache))
+
+	for i := 0; i < 3; i++ {
+		var user TestUser
+		assert.NoError(t, Session(db).Where("id = ?", 6).First(&user).Error)
+		assert.Equal(t, 6, user.ID) // always from the database
+	}
+	var users []TestUser
+	assert.NoError(t, Session(db).Where("id > ?", 95).Find(&users).Error)
+
+	stats := cache.AuditStats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, int64(2), stats.Sets)
+	assert.Equal(t, int64(2), stats.Entries)
+	assert.Greater(t, stats.Bytes, int64(0))
+	assert.Equal(t, 0.5, stats.HitRatio())
+	assert.Empty(t, client.values)
+	assert.Equal(t, Stats{Tables: map[string]TableStats{}, Stages: cache.Stats().Stages}, cache.Stats())
+}
+
+// TestAuditExpiration tests expiring would-be entries
+func TestAuditExpiration(t *testing.T) {
+	var a audit
+	now := time.Now()
+	assert.False(t, a.lookup("a", now))
+	a.set("a", 10, time.Minute, now)
+	a.set("b", 20, 0, now)
+	assert.True(t, a.lookup("a", now.Add(time.Second)))
+	assert.False(t, a.lookup("a", now.Add(time.Minute)))
+
+	stats := a.snapshot(now.Add(time.Minute))
+	assert.Equal(t, AuditStats{Hits: 1, Misses: 2, Sets: 2, Entries: 1, Bytes: 20}, stats)
+}
@@ -0,0 +1,236 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MultiGetter is an optional interface of cache clients which can get multiple keys in one round trip
+type MultiGetter interface {
+	// GetMulti gets values by keys, the values are in the order of keys and nil for missing keys
+	GetMulti(ctx context.Context, keys ...string) ([]interface{}, error)
+}
+
+// MultiTTLGetter is an optional interface of cache clients which can get multiple keys with their remaining ttls in one round trip
+type MultiTTLGetter interface {
+	// GetMultiWithTTL gets values by keys and their remaining ttls, the values are in the order of keys and nil
+	// for missing keys, the ttls are 0 for missing keys and keys without expiration
+	GetMultiWithTTL(ctx context.Context, keys ...string) ([]interface{}, []time.Duration, error)
+}
+
+// MultiSetter is an optional interface of cache clients which can set multiple keys in one round trip
+type MultiSetter interface {
+	// SetMulti sets values by keys with the same ttl
+	SetMulti(ctx context.Context, values map[string]interface{}, ttl time.Duration) error
+}
+
+// getMulti gets values by keys from client, with GetMulti if supported or one Get per key
+func getMulti(ctx context.Context, client CacheClient, keys ...string) ([]interface{}, error) {
+	if getter, ok := client.(MultiGetter); ok {
+		return getter.GetMulti(ctx, keys...)
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, err := client.Get(ctx, key)
+		if err != nil && !isCacheMiss(err) {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// getMultiWithTTL gets values by keys and their remaining ttls from client, with GetMultiWithTTL if supported
+// or getMulti, the ttls are 0 if client can not tell
+func getMultiWithTTL(ctx context.Context, client CacheClient, keys ...string) ([]interface{}, []time.Duration, error) {
+	if getter, ok := client.(MultiTTLGetter); ok {
+		values, ttls, err := getter.GetMultiWithTTL(ctx, keys...)
+		if !errors.Is(err, ErrNotSupported) {
+			return values, ttls, err
+		}
+	}
+	values, err := getMulti(ctx, client, keys...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return values, make([]time.Duration, len(values)), nil
+}
+
+// setMulti sets values by keys to client, with SetMulti if supported or one Set per key
+func setMulti(ctx context.Context, client CacheClient, values map[string]interface{}, ttl time.Duration) error {
+	if setter, ok := client.(MultiSetter); ok {
+		return setter.SetMulti(ctx, values, ttl)
+	}
+
+	for key, value := range values {
+		if err := client.Set(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchWriterOptions is a struct for batch writer options
+type BatchWriterOptions struct {
+	MaxBatch int             // number of queued sets which triggers a write, 0 means 100
+	Interval time.Duration   // maximum time a set is queued, 0 means 10ms
+	OnError  func(err error) // called with errors of background writes, nil logs them with Logger
+	Logger   Logger          // logs errors of background writes without OnError, nil means the standard logger
+}
+
+// BatchWriter is a cache client queueing sets and writing them in batches with one SetMulti per ttl,
+// pipelined in one round trip by RedisClient and MemcachedClient. Queued values are served by Get and dropped by Del,
+// so a queued set never overwrites a later invalidation.
+type BatchWriter struct {
+	client   CacheClient
+	maxBatch int
+	onError  func(err error)
+
+	mu      sync.Mutex
+	pending map[string]batchEntry // queued sets
+	writing map[string]batchEntry // sets being written
+	writeMu sync.Mutex            // held while writing, deletes wait for writes in flight
+
+	kick      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type batchEntry struct {
+	value interface{}
+	ttl   time.Duration
+}
+
+// NewBatchWriter returns a new BatchWriter instance writing to client in the background until Close
+func NewBatchWriter(client CacheClient, options BatchWriterOptions) *BatchWriter {
+	if options.MaxBatch <= 0 {
+		options.MaxBatch = 100
+	}
+	if options.Interval <= 0 {
+		options.Interval = 10 * time.Millisecond
+	}
+	if options.OnError == nil {
+		logger := options.Logger
+		options.OnError = func(err error) {
+			logWith(context.Background(), logger, LogError, "batch write failed", err)
+		}
+	}
+	b := &BatchWriter{
+		client:   client,
+		maxBatch: options.MaxBatch,
+		onError:  options.OnError,
+		pending:  make(map[string]batchEntry),
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run(options.Interval)
+	return b
+}
+
+func (b *BatchWriter) run(interval time.Duration) {
+	defer close(b.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.kick:
+		case <-b.stop:
+			return
+		}
+		if err := b.Flush(context.Background()); err != nil {
+			b.onError(err)
+		}
+	}
+}
+
+// Get gets a queued value by key, or gets value from client
+func (b *BatchWriter) Get(ctx context.Context, key string) (interface{}, error) {
+	b.mu.Lock()
+	entry, ok := b.pending[key]
+	if !ok {
+		entry, ok = b.writing[key]
+	}
+	b.mu.Unlock()
+	if ok {
+		return ValueBytes(entry.value)
+	}
+	return b.client.Get(ctx, key)
+}
+
+// Set queues value by key with ttl, it is written to client within Interval or once MaxBatch sets are queued
+func (b *BatchWriter) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	b.mu.Lock()
+	b.pending[key] = batchEntry{value: value, ttl: ttl}
+	full := len(b.pending) >= b.maxBatch
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Del drops queued sets of keys and deletes keys from client after the writes in flight,
+// returns ErrNotSupported if client does not implement KeyDeleter
+func (b *BatchWriter) Del(ctx context.Context, keys ...string) error {
+	b.mu.Lock()
+	for _, key := range keys {
+		delete(b.pending, key)
+	}
+	b.mu.Unlock()
+
+	deleter, ok := b.client.(KeyDeleter)
+	if !ok {
+		return ErrNotSupported
+	}
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	return deleter.Del(ctx, keys...)
+}
+
+// Flush writes the queued sets to client, one SetMulti per ttl
+func (b *BatchWriter) Flush(ctx context.Context) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	b.mu.Lock()
+	b.writing, b.pending = b.pending, make(map[string]batchEntry)
+	batches := make(map[time.Duration]map[string]interface{})
+	for key, entry := range b.writing {
+		if batches[entry.ttl] == nil {
+			batches[entry.ttl] = make(map[string]interface{})
+		}
+		batches[entry.ttl][key] = entry.value
+	}
+	b.mu.Unlock()
+
+	var firstErr error
+	for ttl, values := range batches {
+		if err := setMulti(ctx, b.client, values, ttl); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	b.mu.Lock()
+	b.writing = nil
+	b.mu.Unlock()
+	return firstErr
+}
+
+// Close stops writing in the background and writes the queued sets
+func (b *BatchWriter) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+	})
+	<-b.done
+	return b.Flush(context.Background())
+}
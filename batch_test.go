@@ -0,0 +1,110 @@
+package grc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMultiFallback tests batch get and set of clients without batch support
+func TestMultiFallback(t *testing.T) {
+	ctx := context.Background()
+	client := newMapClient()
+
+	assert.NoError(t, setMulti(ctx, client, map[string]interface{}{"a": []byte("A"), "b": []byte("B")}, time.Minute))
+	values, err := getMulti(ctx, client, "a", "missing", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte("A"), nil, []byte("B")}, values)
+}
+
+// TestMemoryCacheMulti tests batch get and set of the memory cache
+func TestMemoryCacheMulti(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+	defer cache.Close()
+
+	assert.NoError(t, cache.SetMulti(ctx, map[string]interface{}{"a": []byte("A"), "b": TestUser{ID: 1}}, time.Minute))
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	values, err := cache.GetMulti(ctx, "a", "b", "c", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte("A"), []byte(`{"ID":1,"Name":""}`), nil, nil}, values)
+}
+
+// TestTieredCacheMulti tests batch get and set of the tiered cache
+func TestTieredCacheMulti(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewMemoryCache(), NewMemoryCache()
+	defer l1.Close()
+	defer l2.Close()
+	cache := NewTieredCache(l1, l2, time.Minute)
+
+	assert.NoError(t, cache.SetMulti(ctx, map[string]interface{}{"a": []byte("A")}, time.Hour))
+	assert.Equal(t, []byte("A"), l1.shard("a").items["a"].value)
+	assert.Equal(t, []byte("A"), l2.shard("a").items["a"].value)
+
+	assert.NoError(t, l2.Set(ctx, "b", []byte("B"), time.Hour))
+	values, err := cache.GetMulti(ctx, "a", "b", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte("A"), []byte("B"), nil}, values)
+	assert.Equal(t, []byte("B"), l1.shard("b").items["b"].value) // promoted
+}
+
+// countingMultiSetter counts the SetMulti calls of a memory cache
+type countingMultiSetter struct {
+	*MemoryCache
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *countingMultiSetter) SetMulti(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	return c.MemoryCache.SetMulti(ctx, values, ttl)
+}
+
+// TestBatchWriter tests queueing sets and writing them in batches
+func TestBatchWriter(t *testing.T) {
+	ctx := context.Background()
+	cache := &countingMultiSetter{MemoryCache: NewMemoryCache()}
+	defer cache.Close()
+	writer := NewBatchWriter(cache, BatchWriterOptions{MaxBatch: 3, Interval: time.Hour})
+
+	assert.NoError(t, writer.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, writer.Set(ctx, "b", TestUser{ID: 1}, time.Hour))
+	value, err := writer.Get(ctx, "b") // served from the queue
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"ID":1,"Name":""}`), value)
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, writer.Del(ctx, "b")) // dropped from the queue
+	_, err = writer.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	// a full batch is written in the background, one SetMulti per ttl
+	assert.NoError(t, writer.Set(ctx, "c", []byte("C"), time.Minute))
+	assert.NoError(t, writer.Set(ctx, "d", []byte("D"), time.Hour))
+	assert.Eventually(t, func() bool {
+		_, err := cache.Get(ctx, "d")
+		return err == nil
+	}, time.Second, time.Millisecond)
+	value, err = cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	cache.mu.Lock()
+	assert.Equal(t, 2, cache.calls)
+	cache.mu.Unlock()
+
+	// queued sets are written on close
+	assert.NoError(t, writer.Set(ctx, "e", []byte("E"), time.Minute))
+	assert.NoError(t, writer.Close())
+	value, err = cache.Get(ctx, "e")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("E"), value)
+}
@@ -0,0 +1,207 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by calls rejected by an open circuit breaker
+var ErrCircuitOpen = errors.New("grc: circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // calls pass through
+	CircuitOpen                         // calls are rejected with ErrCircuitOpen
+	CircuitHalfOpen                     // probe calls pass through to test whether the backend recovered
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerOptions is a struct for circuit breaker options
+type CircuitBreakerOptions struct {
+	Window         time.Duration // period calls are counted in, default 10s
+	MinCalls       int           // calls in a window before the breaker can open, default 20
+	ErrorRate      float64       // rate of failed calls in a window opening the breaker, default 0.5
+	SlowCall       time.Duration // calls slower than this count as failed, 0 means latency is not checked
+	OpenDuration   time.Duration // time the breaker stays open before probing, default 30s
+	HalfOpenProbes int           // probe calls in half-open state, all must succeed to close the breaker, default 1
+
+	OnStateChange func(from, to CircuitState) // called on every state change, may be nil
+	Now           func() time.Time            // returns the current time, nil means time.Now
+}
+
+// CircuitBreaker stops calling a failing cache backend for a while, so a degraded backend does not add
+// its timeout to every query. Cache misses are not failures.
+type CircuitBreaker struct {
+	options CircuitBreakerOptions
+
+	mu          sync.Mutex
+	state       CircuitState
+	windowStart time.Time // start of the counting window in closed state
+	calls       int       // calls in the window
+	failures    int       // failed calls in the window
+	openedAt    time.Time // time the breaker opened
+	probes      int       // probe calls started in half-open state
+	successes   int       // successful probe calls in half-open state
+}
+
+// NewCircuitBreaker returns a new CircuitBreaker instance
+func NewCircuitBreaker(options CircuitBreakerOptions) *CircuitBreaker {
+	if options.Window <= 0 {
+		options.Window = 10 * time.Second
+	}
+	if options.MinCalls <= 0 {
+		options.MinCalls = 20
+	}
+	if options.ErrorRate <= 0 {
+		options.ErrorRate = 0.5
+	}
+	if options.OpenDuration <= 0 {
+		options.OpenDuration = 30 * time.Second
+	}
+	if options.HalfOpenProbes <= 0 {
+		options.HalfOpenProbes = 1
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	return &CircuitBreaker{options: options, windowStart: options.Now()}
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && b.options.Now().Sub(b.openedAt) >= b.options.OpenDuration {
+		b.setState(CircuitHalfOpen)
+	}
+	return b.state
+}
+
+// Do calls f if the breaker allows it and records its outcome, returns ErrCircuitOpen otherwise
+func (b *CircuitBreaker) Do(f func() error) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	start := b.options.Now()
+	err := f()
+	failed := (err != nil && !isCacheMiss(err)) ||
+		(b.options.SlowCall > 0 && b.options.Now().Sub(start) > b.options.SlowCall)
+	b.record(failed)
+	return err
+}
+
+// Middleware returns a Middleware calling every operation of the client through the breaker
+func (b *CircuitBreaker) Middleware() Middleware {
+	return func(client CacheClient) CacheClient {
+		next := passThrough(client)
+		c := next
+		c.get = func(ctx context.Context, key string) (value interface{}, err error) {
+			err = b.Do(func() error {
+				value, err = next.get(ctx, key)
+				return err
+			})
+			return value, err
+		}
+		c.set = func(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+			return b.Do(func() error { return next.set(ctx, key, value, ttl) })
+		}
+		c.del = func(ctx context.Context, keys ...string) error {
+			return b.Do(func() error { return next.del(ctx, keys...) })
+		}
+		c.flushPrefix = func(ctx context.Context, prefix string) error {
+			return b.Do(func() error { return next.flushPrefix(ctx, prefix) })
+		}
+		c.wrapOptional(next, func(ctx context.Context, op string, f func() error) error {
+			return b.Do(f)
+		})
+		return c
+	}
+}
+
+// WithCircuitBreaker returns a Middleware calling every operation through a new CircuitBreaker
+func WithCircuitBreaker(options CircuitBreakerOptions) Middleware {
+	return NewCircuitBreaker(options).Middleware()
+}
+
+// allow reports whether a call may start, counting probe calls in half-open state
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.options.Now()
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.options.OpenDuration {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.probes >= b.options.HalfOpenProbes {
+			return false
+		}
+		b.probes++
+		return true
+	default:
+		if now.Sub(b.windowStart) >= b.options.Window {
+			b.windowStart, b.calls, b.failures = now, 0, 0
+		}
+		return true
+	}
+}
+
+// record records the outcome of a call
+func (b *CircuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitHalfOpen:
+		if failed {
+			b.setState(CircuitOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.options.HalfOpenProbes {
+			b.setState(CircuitClosed)
+		}
+	case CircuitClosed:
+		b.calls++
+		if failed {
+			b.failures++
+		}
+		if b.calls >= b.options.MinCalls && float64(b.failures) >= b.options.ErrorRate*float64(b.calls) {
+			b.setState(CircuitOpen)
+		}
+	}
+}
+
+// setState changes the state and resets its counters, b.mu must be held
+func (b *CircuitBreaker) setState(state CircuitState) {
+	from := b.state
+	b.state = state
+	b.probes, b.successes = 0, 0
+	switch state {
+	case CircuitOpen:
+		b.openedAt = b.options.Now()
+	case CircuitClosed:
+		b.windowStart, b.calls, b.failures = b.options.Now(), 0, 0
+	}
+	if b.options.OnStateChange != nil && from != state {
+		b.options.OnStateChange(from, state)
+	}
+}
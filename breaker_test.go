@@ -0,0 +1,96 @@
+package grc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCircuitBreaker tests opening the breaker on errors, probing and closing it when the backend recovers
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	backend := &downCache{MemoryCache: NewMemoryCache()}
+	defer backend.Close()
+
+	var changes []string
+	breaker := NewCircuitBreaker(CircuitBreakerOptions{
+		MinCalls:     4,
+		OpenDuration: time.Minute,
+		Now:          clock.Now,
+		OnStateChange: func(from, to CircuitState) {
+			changes = append(changes, from.String()+" -> "+to.String())
+		},
+	})
+	client := Chain(backend, breaker.Middleware())
+
+	// misses are not failures
+	for i := 0; i < 10; i++ {
+		_, err := client.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	}
+	assert.Equal(t, CircuitClosed, breaker.State())
+
+	atomic.StoreInt32(&backend.down, 1)
+	clock.Advance(10 * time.Second) // a new window
+	for i := 0; i < 4; i++ {
+		assert.ErrorIs(t, client.Set(ctx, "a", []byte("A"), time.Hour), errDown)
+	}
+	assert.Equal(t, CircuitOpen, breaker.State())
+	_, err := client.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, client.(KeyDeleter).Del(ctx, "a"), ErrCircuitOpen)
+
+	// a failed probe opens the breaker again
+	clock.Advance(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, breaker.State())
+	_, err = client.Get(ctx, "a")
+	assert.ErrorIs(t, err, errDown)
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	atomic.StoreInt32(&backend.down, 0)
+	clock.Advance(time.Minute)
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Hour))
+	assert.Equal(t, CircuitClosed, breaker.State())
+	assert.Equal(t, []string{
+		"closed -> open", "open -> half-open", "half-open -> open", "open -> half-open", "half-open -> closed",
+	}, changes)
+}
+
+// TestCircuitBreakerSlowCalls tests counting slow calls as failures and limiting probe calls
+func TestCircuitBreakerSlowCalls(t *testing.T) {
+	clock := newFakeClock()
+	breaker := NewCircuitBreaker(CircuitBreakerOptions{
+		MinCalls:       2,
+		ErrorRate:      1,
+		SlowCall:       100 * time.Millisecond,
+		HalfOpenProbes: 2,
+		Now:            clock.Now,
+	})
+	slow := func() error {
+		clock.Advance(time.Second)
+		return nil
+	}
+
+	assert.NoError(t, breaker.Do(func() error { return nil }))
+	assert.NoError(t, breaker.Do(slow))
+	assert.Equal(t, CircuitClosed, breaker.State())
+	clock.Advance(10 * time.Second)
+	assert.NoError(t, breaker.Do(slow))
+	assert.NoError(t, breaker.Do(slow))
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	// only HalfOpenProbes calls pass in half-open state
+	clock.Advance(30 * time.Second)
+	var inner error
+	assert.NoError(t, breaker.Do(func() error {
+		inner = breaker.Do(func() error { return nil })
+		assert.ErrorIs(t, breaker.Do(func() error { return nil }), ErrCircuitOpen)
+		return nil
+	}))
+	assert.NoError(t, inner)
+	assert.Equal(t, CircuitClosed, breaker.State())
+}
@@ -0,0 +1,90 @@
+package grc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Invalidation is a message of invalidated cache keys and tables sent between instances
+type Invalidation struct {
+	Keys   []string `json:"keys,omitempty"`   // invalidated cache keys
+	Tables []string `json:"tables,omitempty"` // tables whose entries are invalidated
+}
+
+// Broadcaster is an interface for an invalidation bus between instances of a service
+type Broadcaster interface {
+	// Publish sends msg to all other instances
+	Publish(ctx context.Context, msg Invalidation) error
+	// Subscribe calls handler with the messages of other instances until ctx is done
+	Subscribe(ctx context.Context, handler func(msg Invalidation)) error
+}
+
+// RedisBroadcaster is a Broadcaster using redis pub/sub, messages are only delivered to subscribed instances
+type RedisBroadcaster struct {
+	Logger Logger // logs invalid messages, nil means the standard logger
+
+	client  *redis.Client
+	channel string
+	source  string // random id of this instance, its own messages are ignored
+}
+
+// redisInvalidation is an Invalidation published to redis with the id of its source
+type redisInvalidation struct {
+	Source string `json:"source"`
+	Invalidation
+}
+
+// NewRedisBroadcaster returns a new RedisBroadcaster instance publishing to channel
+func NewRedisBroadcaster(client *redis.Client, channel string) *RedisBroadcaster {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &RedisBroadcaster{
+		client:  client,
+		channel: channel,
+		source:  hex.EncodeToString(id),
+	}
+}
+
+// Publish publishes msg to the channel
+func (b *RedisBroadcaster) Publish(ctx context.Context, msg Invalidation) error {
+	data, err := json.Marshal(redisInvalidation{Source: b.source, Invalidation: msg})
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribe subscribes to the channel and calls handler with the messages of other instances until ctx is done
+func (b *RedisBroadcaster) Subscribe(ctx context.Context, handler func(msg Invalidation)) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer pubsub.Close()
+
+	// wait for subscription confirmation
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			var msg redisInvalidation
+			if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				logWith(ctx, b.Logger, LogWarn, "invalid invalidation message", err)
+				continue
+			}
+			if msg.Source != b.source {
+				handler(msg.Invalidation)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRedisBroadcaster tests publishing invalidations to other instances over redis pub/sub
+func TestRedisBroadcaster(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := NewRedisBroadcaster(rdb, "grc:invalidations")
+	b := NewRedisBroadcaster(rdb, "grc:invalidations")
+	received := make(chan Invalidation, 2)
+	go a.Subscribe(ctx, func(msg Invalidation) {
+		received <- msg
+	})
+	time.Sleep(100 * time.Millisecond) // wait for subscription
+
+	assert.NoError(t, a.Publish(ctx, Invalidation{Keys: []string{"own"}})) // ignored by a
+	assert.NoError(t, b.Publish(ctx, Invalidation{Keys: []string{"key"}, Tables: []string{"users"}}))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, Invalidation{Keys: []string{"key"}, Tables: []string{"users"}}, msg)
+	case <-time.After(time.Second):
+		t.Fatal("no invalidation received")
+	}
+}
@@ -2,49 +2,187 @@ package grc
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"gorm.io/gorm/callbacks"
-	"log"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
+// contextKey is the type of context keys, distinct values are required as equal keys shadow each other
+type contextKey string
+
 var (
-	UseCacheKey struct{}
-	CacheTTLKey struct{}
+	UseCacheKey     = contextKey("grc.use_cache")     // bool, whether a query uses the cache
+	CacheTTLKey     = contextKey("grc.cache_ttl")     // time.Duration or string, ttl of the cached result
+	RefreshCacheKey = contextKey("grc.refresh_cache") // bool, skip the cache read of a query using the cache but still cache its result
 )
 
+// ErrCacheMiss is returned by cache clients when the key does not exist
+var ErrCacheMiss = errors.New("grc: cache miss")
+
+// disabled is the process wide kill switch, 1 means all caches are bypassed
+var disabled int32
+
+// SetEnabled enables or disables cache reads and writes of all GormCache instances in the process
+func SetEnabled(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&disabled, 0)
+	} else {
+		atomic.StoreInt32(&disabled, 1)
+	}
+}
+
+// Enabled reports whether caching is enabled process wide
+func Enabled() bool {
+	return atomic.LoadInt32(&disabled) == 0
+}
+
 // GormCache is a cache plugin for gorm
 type GormCache struct {
-	name   string
-	client CacheClient
-	config CacheConfig
+	stats     stats // first field, so its int64 counters are 64-bit aligned for atomic operations
+	name      string
+	client    atomic.Value // clientHolder
+	config    atomic.Value // *CacheConfig
+	disabled  int32
+	unhealthy int32 // set while health checks of the client fail
+
+	revalidating sync.Map // keys of stale entries being revalidated
+	audit        audit    // would-be entries of the audit mode
 }
 
-// CacheClient is an interface for cache operations
+// CacheClient is an interface for cache operations.
+//
+// GormCache encodes values with the codec and stages of its config before calling Set, so Set receives a []byte
+// which must be stored as is, and Get must return the stored bytes as a []byte. Clients must not encode the value
+// again, e.g. with json.Marshal, as earlier versions passing the query destination to Set required; ValueBytes
+// encodes values of other types for clients which are also used directly.
 type CacheClient interface {
 	Get(ctx context.Context, key string) (interface{}, error)
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
 }
 
+// ValueBytes encodes a value passed to CacheClient.Set to json bytes, []byte values are returned as is,
+// for cache clients which store bytes
+func ValueBytes(value interface{}) ([]byte, error) {
+	if data, ok := value.([]byte); ok {
+		return data, nil
+	}
+	return json.Marshal(value)
+}
+
+// clientHolder wraps a CacheClient, so clients of different types can be stored in atomic.Value
+type clientHolder struct {
+	CacheClient
+}
+
 // CacheConfig is a struct for cache options
 type CacheConfig struct {
-	TTL    time.Duration // cache expiration time
-	Prefix string        // cache key prefix
+	TTL                time.Duration            // cache expiration time
+	Prefix             string                   // cache key prefix
+	TTLByTable         map[string]time.Duration // cache expiration time by table name, overrides TTL
+	Tables             []string                 // only cache queries on these tables if not empty
+	ExcludeTables      []string                 // never cache queries on these tables
+	SlidingTTL         bool                     // refresh the ttl of an entry on every cache hit
+	CacheRows          bool                     // also cache Row, Rows and Scan queries, rows are read into memory and replayed
+	CacheRaw           bool                     // also cache raw sql queries, e.g. db.Raw("SELECT ...").Scan(&dest), only SELECT and WITH statements are cached
+	CacheInTransaction bool                     // also cache queries in transactions, which may read uncommitted data
+	CacheAllByDefault  bool                     // cache every query unless opted out with UseCacheKey or the Cache clause
+	MaxValueBytes      int                      // do not cache results whose encoded size exceeds this limit, 0 means no limit
+	CacheEmptyResults  bool                     // also cache empty results, e.g. to protect the database against repeated lookups of missing records
+	EmptyTTL           time.Duration            // cache expiration time of empty results, 0 means the ttl of the query
+	SoftTTL            time.Duration            // revalidate entries in the background on hits after this long, so popular entries never expire
+	StaleTTL           time.Duration            // serve entries for this long after their ttl while they are revalidated in the background
+	VolatileFuncs      []string                 // never cache queries calling these sql functions, nil means DefaultVolatileFuncs
+	InstanceID         string                   // identity of the database mixed into keys, default host, port and database name of the dsn
+	Codec              Codec                    // serialization of cached values, default JSONCodec
+	Stages             []Stage                  // transformations applied in order to encoded values, e.g. compress, encrypt, checksum
+	Broadcaster        Broadcaster              // publishes invalidations to other instances, which drop their local entries
+	FillLocker         FillLocker               // distributed lock around cache population, only the holder queries the database on a miss or revalidates
+	FillLockTTL        time.Duration            // maximum time a fill lock is held, 0 means 10s
+	FillWait           time.Duration            // how long a miss waits for the fill lock holder to populate the entry before querying the database, 0 means 1s
+
+	FailurePolicy        FailurePolicy                // what happens to a query when the cache layer fails, default FailOpen
+	FailurePolicyByClass map[ErrorClass]FailurePolicy // overrides FailurePolicy by error class, e.g. FailClosed on ErrorClassDecode
+
+	Logger    Logger    // structured logger of cache events and failures, nil means failures are logged with the standard logger
+	LogLevels LogLevels // levels of query records of Logger
+	Debug     bool      // log the key, cache decision, outcome and ttl of every query, e.g. to find out why a query is not cached
+	Audit     bool      // never read or write the cache, record would-be hits, misses and entries in AuditStats instead
+
+	OnHit   Hook // called when a query is served from cache
+	OnMiss  Hook // called when a query is not found in cache
+	OnSet   Hook // called when a query result is written to cache
+	OnError Hook // called when a cache operation fails
+}
+
+// ExpiringGetter is an optional interface of cache clients which can get a value and refresh its ttl in one round trip
+type ExpiringGetter interface {
+	GetAndExpire(ctx context.Context, key string, ttl time.Duration) (interface{}, error)
+}
+
+// GetOrSetter is an optional interface of cache clients which can atomically get the value of a key or set it if missing
+type GetOrSetter interface {
+	// GetOrSet gets the value of key, or sets value with ttl if key does not exist, loaded reports whether it existed
+	GetOrSet(ctx context.Context, key string, value interface{}, ttl time.Duration) (actual interface{}, loaded bool, err error)
+}
+
+// CacheClientExt is an optional interface of cache clients which can inspect and refresh the ttl of entries,
+// a ttl of 0 means the entry does not expire, a missing key is reported like a Get miss
+type CacheClientExt interface {
+	CacheClient
+	GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) // gets value and its remaining ttl
+	Touch(ctx context.Context, key string, ttl time.Duration) error                 // sets the ttl of an entry
+	TTL(ctx context.Context, key string) (time.Duration, error)                     // gets the remaining ttl of an entry
+}
+
+// getWithTTL gets the value of key and its remaining ttl from client, the ttl is 0 if client can not tell
+func getWithTTL(ctx context.Context, client CacheClient, key string) (interface{}, time.Duration, error) {
+	if ext, ok := client.(CacheClientExt); ok {
+		value, ttl, err := ext.GetWithTTL(ctx, key)
+		if !errors.Is(err, ErrNotSupported) {
+			return value, ttl, err
+		}
+	}
+	value, err := client.Get(ctx, key)
+	return value, 0, err
 }
 
 // NewGormCache returns a new GormCache instance
 func NewGormCache(name string, client CacheClient, config CacheConfig) *GormCache {
-	return &GormCache{
-		name:   name,
-		client: client,
-		config: config,
+	g := &GormCache{
+		name: name,
 	}
+	g.client.Store(clientHolder{client})
+	g.config.Store(&config)
+	return g
+}
+
+// Client returns the current cache client
+func (g *GormCache) Client() CacheClient {
+	return g.client.Load().(clientHolder).CacheClient
+}
+
+// SetClient atomically replaces the cache client, e.g. to fail over to another backend at runtime
+func (g *GormCache) SetClient(client CacheClient) {
+	g.client.Store(clientHolder{client})
+}
+
+// Config returns the current cache config
+func (g *GormCache) Config() CacheConfig {
+	return *g.config.Load().(*CacheConfig)
+}
+
+// UpdateConfig atomically replaces the cache config, queries started after the call use the new config
+func (g *GormCache) UpdateConfig(config CacheConfig) {
+	g.config.Store(&config)
 }
 
 // Name returns the plugin name
@@ -54,7 +192,25 @@ func (g *GormCache) Name() string {
 
 // Initialize initializes the plugin
 func (g *GormCache) Initialize(db *gorm.DB) error {
-	return db.Callback().Query().Replace("gorm:query", g.queryCallback)
+	if err := db.Callback().Query().Replace("gorm:query", g.queryCallback); err != nil {
+		return err
+	}
+	return db.Callback().Row().Replace("gorm:row", g.rowCallback)
+}
+
+// Disable bypasses all cache reads and writes of this instance, queries go straight to the database
+func (g *GormCache) Disable() {
+	atomic.StoreInt32(&g.disabled, 1)
+}
+
+// Enable re-enables the cache of this instance after Disable
+func (g *GormCache) Enable() {
+	atomic.StoreInt32(&g.disabled, 0)
+}
+
+// Enabled reports whether this instance is enabled, taking the process wide switch into account
+func (g *GormCache) Enabled() bool {
+	return Enabled() && atomic.LoadInt32(&g.disabled) == 0
 }
 
 // queryCallback is a callback function for query operations
@@ -63,7 +219,10 @@ func (g *GormCache) queryCallback(db *gorm.DB) {
 		return
 	}
 
-	enableCache := g.enableCache(db)
+	// take a snapshot of config, so a concurrent update does not affect this query
+	config := g.Config()
+
+	enableCache, reason := g.cacheDecision(db, config)
 
 	// build query sql
 	callbacks.BuildQuerySQL(db)
@@ -71,90 +230,355 @@ func (g *GormCache) queryCallback(db *gorm.DB) {
 		return
 	}
 
+	// check volatile functions, which need the built sql
+	if enableCache && isVolatileSQL(db.Statement.SQL.String(), config) {
+		enableCache, reason = false, "volatile sql function"
+	}
+
 	var (
-		key string
-		err error
-		hit bool
+		key    string
+		err    error
+		hit    bool
+		result = "skip" // outcome of the query logged in debug mode
 	)
+	if config.Debug {
+		defer func() { g.debugQuery(db, config, key, reason, result) }()
+	}
 	if enableCache {
-		key = g.cacheKey(db)
-
+		key = g.cacheKey(db, config)
+		result = "refresh"
+	}
+	if enableCache && config.Audit {
+		result = "audit"
+		g.auditQuery(db, key, config)
+		return
+	}
+	if enableCache && !refreshCache(db) {
 		// get value from cache
-		hit, err = g.loadCache(db, key)
-		if err != nil {
-			log.Printf("load cache failed: %v, hit: %v", err, hit)
-			return
-		}
-
-		// hit cache
-		if hit {
+		var stale bool
+		start := time.Now()
+		hit, stale, err = g.loadCache(db, key, config)
+		event := hookEvent(db, "load", key, start)
+		switch {
+		case err != nil:
+			// query the database unless the failure policy fails the query
+			result = "error"
+			event.Err = err
+			if g.cacheFailed(db, config, event) {
+				return
+			}
+			hit = false
+		case hit:
+			// hit cache
+			result = "hit"
+			g.stats.recordHit()
+			emitHit(db, config, event)
+			if stale {
+				result = "stale hit"
+				g.stats.recordStale()
+				g.revalidate(db, key, config)
+			}
 			return
+		default:
+			result = "miss"
+			g.stats.recordMiss()
+			emitMiss(db, config, event)
+
+			// cache miss, continue database operation
+			//log.Printf("------------------------- miss cache, key: %v", key)
+			if config.FillLocker != nil {
+				var unlock func()
+				if unlock, hit = g.waitFill(db, key, config); hit {
+					result = "filled by another instance"
+					return
+				}
+				defer unlock()
+			}
 		}
-
-		// cache miss, continue database operation
-		//log.Printf("------------------------- miss cache, key: %v", key)
 	}
 
 	if !hit {
 		g.queryDB(db)
 
-		if enableCache {
-			if err = g.setCache(db, key); err != nil {
-				log.Printf("set cache failed: %v", err)
+		// do not cache failed queries
+		if enableCache && cacheable(db, config) {
+			start := time.Now()
+			if err = g.setCache(db, key, config); err != nil {
+				event := hookEvent(db, "set", key, start)
+				event.Err = err
+				g.cacheFailed(db, config, event)
 			}
 		}
 	}
 }
 
-func (g *GormCache) enableCache(db *gorm.DB) bool {
+func (g *GormCache) enableCache(db *gorm.DB, config CacheConfig) bool {
+	enabled, _ := g.cacheDecision(db, config)
+	return enabled
+}
+
+// cacheDecision reports whether the query of db may be cached before its sql is built, and the reason if not
+func (g *GormCache) cacheDecision(db *gorm.DB, config CacheConfig) (bool, string) {
+	// check kill switch and backend health
+	if !g.Enabled() {
+		return false, "cache disabled"
+	}
+	if !g.Healthy() {
+		return false, "cache backend unhealthy"
+	}
+
 	ctx := db.Statement.Context
 
-	// check if use cache
+	// check if use cache, a Cache clause takes precedence over the context, which takes precedence over the config
 	useCache, ok := ctx.Value(UseCacheKey).(bool)
-	if !ok || !useCache {
-		return false // do not use cache, skip this callback
+	if c, found := cacheClause(db); found {
+		useCache, ok = !c.Skip, true
+	}
+	if !ok {
+		useCache = config.CacheAllByDefault
+	}
+	if !useCache {
+		return false, "cache not used by the query" // do not use cache, skip this callback
+	}
+
+	// check raw sql, which is built before callbacks
+	if isRawQuery(db) {
+		if !config.CacheRaw {
+			return false, "raw sql not cached without CacheRaw"
+		}
+		if !isReadOnlySQL(db.Statement.SQL.String()) {
+			return false, "raw sql is not a query"
+		}
+	}
+
+	// check table filters
+	table := db.Statement.Table
+	if containsString(config.ExcludeTables, table) {
+		return false, "table " + table + " excluded"
+	}
+	if len(config.Tables) > 0 && !containsString(config.Tables, table) {
+		return false, "table " + table + " not in Tables"
+	}
+
+	// check locking clause, the lock is only acquired by querying the database
+	if isLockingRead(db) {
+		return false, "locking read"
+	}
+
+	// check transaction, queries in a transaction may read uncommitted data
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok && !config.CacheInTransaction {
+		return false, "query in a transaction"
 	}
-	return true
+	return true, ""
 }
 
-func (g *GormCache) cacheKey(db *gorm.DB) string {
-	sql := db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)
-	hash := sha256.Sum256([]byte(sql))
-	key := g.config.Prefix + hex.EncodeToString(hash[:])
+func (g *GormCache) cacheKey(db *gorm.DB, config CacheConfig) string {
+	prefix := tablePrefix(config, db.Statement.Table)
+	if _, ok := countDest(db); ok {
+		prefix += "count:"
+	}
+	key := prefix + hashQuery(instanceID(db, config), db.Statement.SQL.String(), db.Statement.Vars)
 	//log.Printf("key: %v, sql: %v", key, sql)
 	return key
 }
 
-func (g *GormCache) loadCache(db *gorm.DB, key string) (bool, error) {
-	value, err := g.client.Get(db.Statement.Context, key)
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return false, err
+// tablePrefix returns the key prefix of queries on table, so entries of a table can be invalidated together
+func tablePrefix(config CacheConfig, table string) string {
+	if table == "" {
+		return config.Prefix
+	}
+	return config.Prefix + table + ":"
+}
+
+// loadCache gets the entry of key and scans it to the destination of db, a stale entry is a hit which needs revalidation
+func (g *GormCache) loadCache(db *gorm.DB, key string, config CacheConfig) (hit bool, stale bool, err error) {
+	data, err := g.get(db, key, config)
+	if err != nil || data == nil {
+		return false, false, err
 	}
+	data, stale = unwrapEntry(data, time.Now())
+	hit, err = g.scanCache(db, key, data, config)
+	return hit, stale, classify(ErrorClassDecode, err)
+}
 
+// get gets the data of key from the cache client, nil means a cache miss. With SlidingTTL the ttl of the entry
+// is refreshed along with get if the client is an ExpiringGetter, or after get if the client is a CacheClientExt.
+func (g *GormCache) get(db *gorm.DB, key string, config CacheConfig) ([]byte, error) {
+	var (
+		value interface{}
+		err   error
+	)
+	ctx := db.Statement.Context
+	client := g.Client()
+	getter, expiring := client.(ExpiringGetter)
+	if config.SlidingTTL && expiring {
+		_, ttl := entryTTL(config, g.cacheTTL(db, config))
+		value, err = getter.GetAndExpire(ctx, key, ttl)
+		expiring = !errors.Is(err, ErrNotSupported) // a middleware wrapping a client which is not an ExpiringGetter
+	}
+	if !config.SlidingTTL || !expiring {
+		value, err = client.Get(ctx, key)
+	}
+	if err != nil && !isCacheMiss(err) {
+		return nil, err
+	}
 	if value == nil {
-		return false, nil
+		return nil, nil
+	}
+	if ext, ok := client.(CacheClientExt); ok && config.SlidingTTL && !expiring {
+		_, ttl := entryTTL(config, g.cacheTTL(db, config))
+		if err = ext.Touch(ctx, key, ttl); err != nil && !isCacheMiss(err) && !errors.Is(err, ErrNotSupported) {
+			logMessage(ctx, config, LogWarn, "touch cache failed", err)
+		}
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return nil, classify(ErrorClassDecode, fmt.Errorf("unexpected cache value type %T", value))
+	}
+	return data, nil
+}
+
+// scanCache scans the cached data of key to the destination of db
+func (g *GormCache) scanCache(db *gorm.DB, key string, data []byte, config CacheConfig) (bool, error) {
+	var err error
+
+	// cache hit of an empty result, no codec encodes a value to zero bytes
+	if len(data) == 0 {
+		if rv := db.Statement.ReflectValue; rv.Kind() == reflect.Slice && rv.CanSet() {
+			rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+		}
+		db.RowsAffected = 0
+		if db.Statement.RaiseErrorOnNotFound {
+			db.AddError(gorm.ErrRecordNotFound)
+		}
+		return true, nil
 	}
 
 	// cache hit, scan value to destination
-	if err = json.Unmarshal(value.([]byte), &db.Statement.Dest); err != nil {
+	if count, ok := countDest(db); ok {
+		var entry countEntry
+		if err = g.decode(config, key, data, &entry); err != nil {
+			return false, err
+		}
+		*count = entry.Count
+		db.RowsAffected = entry.RowsAffected
+		return true, nil
+	}
+	if _, ok := mapRows(db.Statement.Dest); ok {
+		var entry mapEntry
+		if err = g.decode(config, key, data, &entry); err != nil {
+			return false, err
+		}
+		if db.RowsAffected, err = decodeMapRows(entry, db.Statement.Dest); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if err = g.decode(config, key, data, db.Statement.Dest); err != nil {
 		return false, err
 	}
-	db.RowsAffected = int64(db.Statement.ReflectValue.Len())
+	db.RowsAffected = rowsAffected(db.Statement.ReflectValue)
 	return true, nil
 }
 
-func (g *GormCache) setCache(db *gorm.DB, key string) error {
-	ctx := db.Statement.Context
+// isCacheMiss reports whether err means the key does not exist
+func isCacheMiss(err error) bool {
+	return errors.Is(err, redis.Nil) || errors.Is(err, ErrCacheMiss)
+}
 
-	// get cache ttl from context or config
-	ttl, ok := ctx.Value(CacheTTLKey).(time.Duration)
-	if !ok {
-		ttl = g.config.TTL // use default ttl
+// rowsAffected returns the number of rows in a cached destination, a slice holds its length, a struct holds one row
+func rowsAffected(value reflect.Value) int64 {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		return int64(value.Len())
+	case reflect.Invalid:
+		return 0
+	default:
+		return 1
 	}
+}
+
+func (g *GormCache) setCache(db *gorm.DB, key string, config CacheConfig) error {
+	ttl := g.cacheTTL(db, config)
 	//log.Printf("ttl: %v", ttl)
 
+	value := db.Statement.Dest
+	if count, ok := countDest(db); ok {
+		value = countEntry{Count: *count, RowsAffected: db.RowsAffected}
+	} else if db.RowsAffected == 0 {
+		// empty result, cached as an empty value
+		if !config.CacheEmptyResults {
+			return nil
+		}
+		return g.setData(db, key, config, []byte{}, emptyTTL(config, ttl))
+	} else if rows, ok := mapRows(db.Statement.Dest); ok {
+		entry, err := encodeMapRows(rows)
+		if err != nil {
+			return classify(ErrorClassEncode, err)
+		}
+		value = entry
+	}
+
+	return g.set(db, key, config, value, ttl)
+}
+
+// emptyTTL returns the ttl of empty results
+func emptyTTL(config CacheConfig, ttl time.Duration) time.Duration {
+	if config.EmptyTTL > 0 {
+		return config.EmptyTTL
+	}
+	return ttl
+}
+
+// set encodes value and sets it to cache with ttl
+func (g *GormCache) set(db *gorm.DB, key string, config CacheConfig, value interface{}, ttl time.Duration) error {
+	data, err := g.encode(config, key, value)
+	if err != nil {
+		return classify(ErrorClassEncode, err)
+	}
+	return g.setData(db, key, config, data, ttl)
+}
+
+// setData sets encoded data to cache with ttl, data larger than MaxValueBytes is skipped
+func (g *GormCache) setData(db *gorm.DB, key string, config CacheConfig, data []byte, ttl time.Duration) error {
+	if config.MaxValueBytes > 0 && len(data) > config.MaxValueBytes {
+		g.stats.recordOversized()
+		return nil
+	}
+	// entries are revalidated after SoftTTL and retained for StaleTTL after their ttl, the freshness is kept in a header
+	if fresh, hard := entryTTL(config, ttl); fresh != hard {
+		data = wrapEntry(data, time.Now().Add(fresh))
+		ttl = hard
+	}
+
+	if config.Audit {
+		g.audit.set(key, len(data), ttl, time.Now())
+		return nil
+	}
+
 	// set value to cache with ttl
-	return g.client.Set(ctx, key, db.Statement.Dest, ttl)
+	start := time.Now()
+	if err := g.Client().Set(db.Statement.Context, key, data, ttl); err != nil {
+		return err
+	}
+	g.stats.recordSet(db.Statement.Table, len(data))
+	emitSet(db, config, hookEvent(db, "set", key, start))
+	return nil
+}
+
+// cacheTTL gets cache ttl from Cache clause, context or config
+func (g *GormCache) cacheTTL(db *gorm.DB, config CacheConfig) time.Duration {
+	if c, ok := cacheClause(db); ok && c.TTL > 0 {
+		return c.TTL
+	}
+	ttl, ok := contextTTL(db.Statement.Context, config)
+	if !ok {
+		if ttl, ok = config.TTLByTable[db.Statement.Table]; !ok {
+			ttl = config.TTL // use default ttl
+		}
+	}
+	return ttl
 }
 
 func (g *GormCache) queryDB(db *gorm.DB) {
@@ -169,34 +593,53 @@ func (g *GormCache) queryDB(db *gorm.DB) {
 	gorm.Scan(rows, db, 0)
 }
 
-// RedisClient is a wrapper for go-redis client
-type RedisClient struct {
-	client *redis.Client
+// refreshCache reports whether the Cache clause or the context asks to skip cache reads
+func refreshCache(db *gorm.DB) bool {
+	if c, ok := cacheClause(db); ok && c.Refresh {
+		return true
+	}
+	refresh, _ := db.Statement.Context.Value(RefreshCacheKey).(bool)
+	return refresh
 }
 
-// NewRedisClient returns a new RedisClient instance
-func NewRedisClient(client *redis.Client) *RedisClient {
-	return &RedisClient{
-		client: client,
+// contextTTL gets cache ttl from context, the value can be a time.Duration or a string accepted by ParseTTL,
+// invalid strings are logged with the Logger of config
+func contextTTL(ctx context.Context, config CacheConfig) (time.Duration, bool) {
+	switch v := ctx.Value(CacheTTLKey).(type) {
+	case time.Duration:
+		return v, true
+	case string:
+		ttl, err := ParseTTL(v)
+		if err != nil {
+			logMessage(ctx, config, LogWarn, "invalid cache ttl", err)
+			return 0, false
+		}
+		return ttl, true
+	default:
+		return 0, false
 	}
 }
 
-// Get gets value from redis by key using json encoding/decoding
-func (r *RedisClient) Get(ctx context.Context, key string) (interface{}, error) {
-	data, err := r.client.Get(ctx, key).Bytes()
-	if err != nil {
-		return nil, err
+// ParseTTL parses a human-readable ttl string, either a duration like "30s" and "5m" or a number of seconds like "300".
+// Negative ttls are rejected, as clients treat ttls <= 0 as no expiration and a typo would cache entries forever.
+func ParseTTL(s string) (time.Duration, error) {
+	var ttl time.Duration
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		ttl = time.Duration(seconds) * time.Second
+	} else if ttl, err = time.ParseDuration(s); err != nil {
+		return 0, fmt.Errorf("invalid ttl %q", s)
 	}
-	//log.Printf("get cache, key: %v", key)
-	return data, nil
+	if ttl < 0 {
+		return 0, fmt.Errorf("invalid ttl %q: negative", s)
+	}
+	return ttl, nil
 }
 
-// Set sets value to redis by key with ttl using json encoding/decoding
-func (r *RedisClient) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	//log.Printf("set cache, key: %v", key)
-	data, err := json.Marshal(value) // encode value to json bytes using json encoding/decoding
-	if err != nil {
-		return err
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
-	return r.client.Set(ctx, key, data, ttl).Err()
+	return false
 }
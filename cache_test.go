@@ -1,10 +1,12 @@
 package grc
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -130,3 +132,451 @@ func BenchmarkCache(b *testing.B) {
 		db.Session(&gorm.Session{Context: context.WithValue(context.Background(), UseCacheKey, true)}).Where("id > ?", 10).Find(&users)
 	}
 }
+
+// TestUpdateConfig tests replacing the config at runtime
+func TestUpdateConfig(t *testing.T) {
+	cache := NewGormCache("my_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "cache:",
+	})
+	assert.Equal(t, 60*time.Second, cache.Config().TTL)
+
+	cache.UpdateConfig(CacheConfig{
+		TTL:    10 * time.Second,
+		Prefix: "cache2:",
+	})
+	assert.Equal(t, 10*time.Second, cache.Config().TTL)
+	assert.Equal(t, "cache2:", cache.Config().Prefix)
+}
+
+// TestKillSwitch tests disabling the cache per instance and process wide
+func TestKillSwitch(t *testing.T) {
+	cache := NewGormCache("my_cache", NewRedisClient(rdb), CacheConfig{})
+	assert.True(t, cache.Enabled())
+
+	cache.Disable()
+	assert.False(t, cache.Enabled())
+	cache.Enable()
+	assert.True(t, cache.Enabled())
+
+	SetEnabled(false)
+	assert.False(t, Enabled())
+	assert.False(t, cache.Enabled())
+	SetEnabled(true)
+	assert.True(t, cache.Enabled())
+}
+
+// TestSetClient tests replacing the cache client at runtime
+func TestSetClient(t *testing.T) {
+	client := NewRedisClient(rdb)
+	cache := NewGormCache("my_cache", client, CacheConfig{})
+	assert.Same(t, client, cache.Client())
+
+	other := NewRedisClient(rdb)
+	cache.SetClient(other)
+	assert.Same(t, other, cache.Client())
+}
+
+// TestParseTTL tests parsing human-readable ttl strings
+func TestParseTTL(t *testing.T) {
+	args := []struct {
+		Value string
+		TTL   time.Duration
+		Err   bool
+	}{
+		{Value: "30s", TTL: 30 * time.Second},
+		{Value: "5m", TTL: 5 * time.Minute},
+		{Value: "300", TTL: 300 * time.Second},
+		{Value: "soon", Err: true},
+		{Value: "-5", Err: true},
+		{Value: "-1m", Err: true},
+		{Value: "0", TTL: 0},
+	}
+
+	for _, arg := range args {
+		ttl, err := ParseTTL(arg.Value)
+		if arg.Err {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, arg.TTL, ttl)
+	}
+
+	ttl, ok := contextTTL(context.WithValue(context.Background(), CacheTTLKey, "1m"), CacheConfig{})
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, ttl)
+}
+
+// TestRedisBinaryValues tests round trips of values containing CRLF, RESP markers and zero bytes,
+// and of a value large enough to be read from several TCP segments
+func TestRedisBinaryValues(t *testing.T) {
+	ctx := context.Background()
+	client := NewRedisClient(rdb)
+
+	values := map[string][]byte{
+		"binary:crlf":  []byte("A\r\nB\r\n"),
+		"binary:resp":  []byte("$5\r\nhello\r\n*2\r\n:1\r\n-ERR x\r\n"),
+		"binary:bytes": {0, '\r', '\n', 0xff, 0, '\n'},
+		"binary:large": bytes.Repeat([]byte("\r\n$-1\r\n\x00"), 1<<17),
+	}
+	for key, value := range values {
+		assert.NoError(t, client.Set(ctx, key, value, time.Minute))
+		got, err := client.Get(ctx, key)
+		assert.NoError(t, err)
+		assert.Equal(t, value, got, key)
+	}
+
+	got, err := client.GetMulti(ctx, "binary:crlf", "binary:large")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{values["binary:crlf"], values["binary:large"]}, got)
+}
+
+// TestRedisGetMulti tests getting and setting multiple keys in one round trip
+func TestRedisGetMulti(t *testing.T) {
+	ctx := context.Background()
+	client := NewRedisClient(rdb)
+
+	assert.NoError(t, client.Set(ctx, "multi:a", "A", time.Minute))
+	assert.NoError(t, client.Set(ctx, "multi:b", "B", time.Minute))
+
+	values, err := client.GetMulti(ctx, "multi:a", "multi:missing", "multi:b")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte(`"A"`), nil, []byte(`"B"`)}, values)
+
+	assert.NoError(t, client.SetMulti(ctx, map[string]interface{}{"multi:c": []byte("C"), "multi:d": "D"}, time.Minute))
+	values, err = client.GetMulti(ctx, "multi:c", "multi:d")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte("C"), []byte(`"D"`)}, values)
+	assert.Greater(t, rdb.TTL(ctx, "multi:c").Val(), time.Duration(0))
+
+	assert.NoError(t, client.Set(ctx, "multi:e", []byte("E"), 0))
+	values, ttls, err := client.GetMultiWithTTL(ctx, "multi:c", "multi:missing", "multi:e")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte("C"), nil, []byte("E")}, values)
+	assert.InDelta(t, time.Minute, ttls[0], float64(time.Second))
+	assert.Equal(t, []time.Duration{ttls[0], 0, 0}, ttls)
+}
+
+// BenchmarkRedisClient benchmarks the allocations of redis client operations
+func BenchmarkRedisClient(b *testing.B) {
+	ctx := context.Background()
+	client := NewRedisClient(rdb)
+	users := []TestUser{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		client.Set(ctx, "bench:users", users, time.Minute)
+		client.Get(ctx, "bench:users")
+	}
+}
+
+// TestRedisGetAndExpire tests refreshing ttl along with get
+func TestRedisGetAndExpire(t *testing.T) {
+	ctx := context.Background()
+	client := NewRedisClient(rdb)
+
+	assert.NoError(t, client.Set(ctx, "sliding:a", "A", time.Second))
+
+	value, err := client.GetAndExpire(ctx, "sliding:a", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`"A"`), value)
+	assert.Greater(t, rdb.TTL(ctx, "sliding:a").Val(), time.Second)
+
+	_, err = client.GetAndExpire(ctx, "sliding:missing", time.Minute)
+	assert.ErrorIs(t, err, redis.Nil)
+}
+
+// TestRedisGetOrSet tests atomically getting or setting a value in redis
+func TestRedisGetOrSet(t *testing.T) {
+	ctx := context.Background()
+	client := NewRedisClient(rdb)
+	defer rdb.Del(ctx, "getorset:a", "getorset:b")
+
+	value, loaded, err := client.GetOrSet(ctx, "getorset:a", []byte("A"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, loaded)
+	assert.Equal(t, []byte("A"), value)
+	assert.Greater(t, rdb.PTTL(ctx, "getorset:a").Val(), 59*time.Second)
+
+	value, loaded, err = client.GetOrSet(ctx, "getorset:a", []byte("B"), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, loaded)
+	assert.Equal(t, []byte("A"), value)
+
+	_, loaded, err = client.GetOrSet(ctx, "getorset:b", TestUser{ID: 1}, 0)
+	assert.NoError(t, err)
+	assert.False(t, loaded)
+	assert.Equal(t, time.Duration(-1), rdb.TTL(ctx, "getorset:b").Val()) // no expiration
+}
+
+// TestRedisClientExt tests ttl introspection and touch of the redis client
+func TestRedisClientExt(t *testing.T) {
+	ctx := context.Background()
+	var client CacheClientExt = NewRedisClient(rdb)
+
+	assert.NoError(t, client.Set(ctx, "ext:a", "A", time.Minute))
+	value, ttl, err := client.GetWithTTL(ctx, "ext:a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`"A"`), value)
+	assert.InDelta(t, float64(time.Minute), float64(ttl), float64(time.Second))
+
+	assert.NoError(t, client.Touch(ctx, "ext:a", time.Hour))
+	ttl, err = client.TTL(ctx, "ext:a")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+
+	assert.NoError(t, client.Touch(ctx, "ext:a", 0))
+	ttl, err = client.TTL(ctx, "ext:a")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	_, _, err = client.GetWithTTL(ctx, "ext:missing")
+	assert.ErrorIs(t, err, redis.Nil)
+	assert.ErrorIs(t, client.Touch(ctx, "ext:missing", time.Minute), redis.Nil)
+	assert.ErrorIs(t, client.Touch(ctx, "ext:missing", 0), redis.Nil)
+	_, err = client.TTL(ctx, "ext:missing")
+	assert.ErrorIs(t, err, redis.Nil)
+}
+
+// TestCacheStruct tests caching struct destinations
+func TestCacheStruct(t *testing.T) {
+	cache := NewGormCache("struct_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "struct:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	for i := 0; i < 2; i++ {
+		var user TestUser
+		result := db.Session(&gorm.Session{Context: ctx}).Where("id = ?", 10).First(&user)
+		assert.NoError(t, result.Error)
+		assert.Equal(t, int64(1), result.RowsAffected)
+		assert.Equal(t, 10, user.ID)
+
+		// not found is not cached
+		result = db.Session(&gorm.Session{Context: ctx}).Where("id = ?", userCount+1).First(&user)
+		assert.ErrorIs(t, result.Error, gorm.ErrRecordNotFound)
+	}
+}
+
+// TestRowsAffected tests counting rows of cached destinations
+func TestRowsAffected(t *testing.T) {
+	assert.Equal(t, int64(2), rowsAffected(reflect.ValueOf([]TestUser{{}, {}})))
+	assert.Equal(t, int64(1), rowsAffected(reflect.ValueOf(TestUser{})))
+	assert.Equal(t, int64(0), rowsAffected(reflect.Value{}))
+}
+
+// TestCacheRaw tests caching raw sql queries only when opted in
+func TestCacheRaw(t *testing.T) {
+	cache := NewGormCache("raw_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "raw:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	query := func() {
+		var users []TestUser
+		assert.NoError(t, db.Session(&gorm.Session{Context: ctx}).Raw("SELECT * FROM test_users WHERE id <= ?", 3).Find(&users).Error)
+		assert.Len(t, users, 3)
+	}
+
+	query()
+	query()
+	assert.Equal(t, int64(0), cache.Stats().Hits+cache.Stats().Misses)
+
+	config := cache.Config()
+	config.CacheRaw = true
+	cache.UpdateConfig(config)
+	query()
+	query()
+	assert.Equal(t, int64(1), cache.Stats().Misses)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+}
+
+// TestCacheTransaction tests bypassing cache in transactions
+func TestCacheTransaction(t *testing.T) {
+	cache := NewGormCache("tx_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "tx:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	err := db.Session(&gorm.Session{Context: ctx}).Transaction(func(tx *gorm.DB) error {
+		var users []TestUser
+		return tx.Where("id > ?", 10).Find(&users).Error
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), cache.Stats().Misses)
+
+	config := cache.Config()
+	config.CacheInTransaction = true
+	cache.UpdateConfig(config)
+	err = db.Session(&gorm.Session{Context: ctx}).Transaction(func(tx *gorm.DB) error {
+		var users []TestUser
+		return tx.Where("id > ?", 10).Find(&users).Error
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), cache.Stats().Misses)
+}
+
+// TestRefreshCache tests skipping cache reads and overwriting the cached entry
+func TestRefreshCache(t *testing.T) {
+	cache := NewGormCache("refresh_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "refresh:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	query := func(ctx context.Context) string {
+		var user TestUser
+		assert.NoError(t, db.Session(&gorm.Session{Context: ctx}).Where("id = ?", 1).First(&user).Error)
+		return user.Name
+	}
+
+	assert.Equal(t, "41", query(ctx))
+	assert.NoError(t, db.Model(&TestUser{}).Where("id = ?", 1).Update("name", "refreshed").Error)
+	defer db.Model(&TestUser{}).Where("id = ?", 1).Update("name", "41")
+
+	assert.Equal(t, "41", query(ctx))
+	assert.Equal(t, "refreshed", query(context.WithValue(ctx, RefreshCacheKey, true)))
+	assert.Equal(t, "refreshed", query(ctx))
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(2), stats.Sets)
+}
+
+// TestContextKeys tests that context keys do not shadow each other
+func TestContextKeys(t *testing.T) {
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	ctx = context.WithValue(ctx, CacheTTLKey, time.Minute)
+	ctx = context.WithValue(ctx, RefreshCacheKey, false)
+
+	assert.Equal(t, true, ctx.Value(UseCacheKey))
+	assert.Equal(t, time.Minute, ctx.Value(CacheTTLKey))
+	assert.Equal(t, false, ctx.Value(RefreshCacheKey))
+}
+
+// TestCacheAllByDefault tests caching queries without opting in
+func TestCacheAllByDefault(t *testing.T) {
+	cache := NewGormCache("default_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:               60 * time.Second,
+		Prefix:            "default:",
+		CacheAllByDefault: true,
+	})
+	assert.NoError(t, db.Use(cache))
+
+	query := func(tx *gorm.DB) {
+		var users []TestUser
+		assert.NoError(t, tx.Where("id > ?", 10).Find(&users).Error)
+		assert.Len(t, users, userCount-10)
+	}
+
+	query(db)
+	query(db)
+	assert.Equal(t, int64(1), cache.Stats().Misses)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+
+	query(db.WithContext(Disable(context.Background())))
+	query(db.Clauses(Cache{Skip: true}))
+	assert.Equal(t, int64(1), cache.Stats().Misses)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+}
+
+// TestCacheTTLByTable tests the precedence of ttl sources
+func TestCacheTTLByTable(t *testing.T) {
+	cache := NewGormCache("ttl_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:        time.Second,
+		TTLByTable: map[string]time.Duration{"test_users": time.Hour},
+	})
+	dryDB := db.Session(&gorm.Session{DryRun: true})
+	config := cache.Config()
+
+	stmt := dryDB.Find(&[]TestUser{}).Statement
+	assert.Equal(t, time.Hour, cache.cacheTTL(stmt.DB, config))
+
+	stmt = dryDB.Table("other_users").Find(&[]TestUser{}).Statement
+	assert.Equal(t, time.Second, cache.cacheTTL(stmt.DB, config))
+
+	stmt = dryDB.WithContext(WithTTL(context.Background(), time.Minute)).Find(&[]TestUser{}).Statement
+	assert.Equal(t, time.Minute, cache.cacheTTL(stmt.DB, config))
+
+	stmt = dryDB.WithContext(WithTTL(context.Background(), time.Minute)).Clauses(Cache{TTL: 2 * time.Minute}).Find(&[]TestUser{}).Statement
+	assert.Equal(t, 2*time.Minute, cache.cacheTTL(stmt.DB, config))
+}
+
+// TestMaxValueBytes tests skipping results larger than the limit
+func TestMaxValueBytes(t *testing.T) {
+	cache := NewGormCache("max_value_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:           60 * time.Second,
+		Prefix:        "max_value:",
+		MaxValueBytes: 64,
+	})
+	assert.NoError(t, db.Use(cache))
+
+	session := Session(db)
+	for i := 0; i < 2; i++ {
+		var users []TestUser
+		assert.NoError(t, session.Find(&users).Error)
+		assert.Len(t, users, userCount)
+	}
+	assert.Equal(t, int64(2), cache.Stats().Oversized)
+	assert.Equal(t, int64(0), cache.Stats().Sets)
+
+	for i := 0; i < 2; i++ {
+		var user TestUser
+		assert.NoError(t, session.Where("id = ?", 1).First(&user).Error)
+		assert.Equal(t, "41", user.Name)
+	}
+	assert.Equal(t, int64(1), cache.Stats().Sets)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+}
+
+// TestCacheEmptyResults tests caching empty results only when configured
+func TestCacheEmptyResults(t *testing.T) {
+	cache := NewGormCache("empty_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "empty:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	session := Session(db)
+	find := func() {
+		users := []TestUser{{ID: 1}}
+		result := session.Where("id > ?", 1000).Find(&users)
+		assert.NoError(t, result.Error)
+		assert.Equal(t, int64(0), result.RowsAffected)
+		assert.Empty(t, users)
+	}
+	first := func() {
+		var user TestUser
+		assert.ErrorIs(t, session.Where("id = ?", 1000).First(&user).Error, gorm.ErrRecordNotFound)
+	}
+
+	find()
+	find()
+	first()
+	first()
+	assert.Equal(t, int64(0), cache.Stats().Sets)
+	assert.Equal(t, int64(0), cache.Stats().Hits)
+
+	config := cache.Config()
+	config.CacheEmptyResults = true
+	config.EmptyTTL = 5 * time.Second
+	cache.UpdateConfig(config)
+
+	find()
+	find()
+	first()
+	first()
+	assert.Equal(t, int64(2), cache.Stats().Sets)
+	assert.Equal(t, int64(2), cache.Stats().Hits)
+}
@@ -0,0 +1,37 @@
+package grc
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// cacheSettingKey is the statement setting of a Cache clause
+const cacheSettingKey = "grc:cache"
+
+// Cache is a clause to control the cache of a query as an alternative to context values,
+// e.g. db.Clauses(grc.Cache{TTL: time.Minute}).Find(&users), it takes precedence over context values
+type Cache struct {
+	TTL     time.Duration // ttl of the cached result, 0 means the ttl of the context or config
+	Refresh bool          // skip the cache read but still cache the result, like RefreshCacheKey
+	Skip    bool          // do not use the cache, like UseCacheKey with false
+}
+
+// ModifyStatement stores the clause in the statement settings, implementing gorm.StatementModifier
+func (c Cache) ModifyStatement(stmt *gorm.Statement) {
+	stmt.Settings.Store(cacheSettingKey, c)
+}
+
+// Build implements clause.Expression, the clause writes no sql
+func (c Cache) Build(clause.Builder) {}
+
+// cacheClause returns the Cache clause of a query
+func cacheClause(db *gorm.DB) (Cache, bool) {
+	v, ok := db.Statement.Settings.Load(cacheSettingKey)
+	if !ok {
+		return Cache{}, false
+	}
+	c, ok := v.(Cache)
+	return c, ok
+}
@@ -0,0 +1,57 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestCacheClauseTTL tests reading the Cache clause of a statement
+func TestCacheClauseTTL(t *testing.T) {
+	cache := NewGormCache("clause_ttl_cache", NewRedisClient(rdb), CacheConfig{TTL: time.Second})
+	dryDB := db.Session(&gorm.Session{DryRun: true, Context: context.WithValue(context.Background(), CacheTTLKey, time.Hour)})
+
+	stmt := dryDB.Clauses(Cache{TTL: time.Minute}).Find(&[]TestUser{}).Statement
+	c, ok := cacheClause(stmt.DB)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, c.TTL)
+	assert.Equal(t, time.Minute, cache.cacheTTL(stmt.DB, cache.Config()))
+	assert.NotContains(t, stmt.SQL.String(), "WHERE")
+
+	stmt = dryDB.Clauses(Cache{Refresh: true}).Find(&[]TestUser{}).Statement
+	assert.Equal(t, time.Hour, cache.cacheTTL(stmt.DB, cache.Config()))
+	assert.True(t, refreshCache(stmt.DB))
+
+	stmt = dryDB.Find(&[]TestUser{}).Statement
+	_, ok = cacheClause(stmt.DB)
+	assert.False(t, ok)
+	assert.False(t, refreshCache(stmt.DB))
+}
+
+// TestCacheClause tests controlling the cache of queries with the Cache clause
+func TestCacheClause(t *testing.T) {
+	cache := NewGormCache("clause_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "clause:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	query := func(tx *gorm.DB) {
+		var users []TestUser
+		assert.NoError(t, tx.Where("id > ?", 10).Find(&users).Error)
+		assert.Len(t, users, userCount-10)
+	}
+
+	query(db.Clauses(Cache{TTL: time.Minute}))
+	query(db.Clauses(Cache{TTL: time.Minute}))
+	assert.Equal(t, int64(1), cache.Stats().Misses)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+
+	// the clause overrides the context
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	query(db.WithContext(ctx).Clauses(Cache{Skip: true}))
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+}
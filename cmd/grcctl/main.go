@@ -0,0 +1,184 @@
+// Command grcctl inspects and manages the entries of a grc cache, e.g. to debug stale data incidents.
+//
+// It connects to the backend of a config file given with -config, or of the GRC_* environment variables:
+//
+//	grcctl keys [prefix]        lists keys under prefix, the configured prefix by default, with ttls and payload summaries
+//	grcctl get key              shows the ttl, size and decoded payload of an entry
+//	grcctl del key...           deletes entries
+//	grcctl flush prefix         deletes all entries under prefix
+//	grcctl export prefix        writes a dump of the entries under prefix to stdout
+//	grcctl import               sets the entries of a dump read from stdin
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/evangwt/grc"
+)
+
+// summaryLength is the maximum length of payload summaries in key listings
+const summaryLength = 60
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: grcctl [-config file] [-limit n] keys [prefix] | get key | del key... | flush prefix | export prefix | import\n")
+		flag.PrintDefaults()
+	}
+	path := flag.String("config", "", "yaml or json config file, GRC_* environment variables are used if empty")
+	limit := flag.Int("limit", 100, "maximum number of keys listed, 0 means all")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of the command")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := loadConfig(*path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "grcctl:", err)
+		os.Exit(1)
+	}
+	cache, err := config.NewGormCache("grcctl")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "grcctl:", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	err = run(ctx, cache, *limit, flag.Args(), os.Stdin, os.Stdout)
+	cancel()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "grcctl:", err)
+		os.Exit(1)
+	}
+}
+
+func loadConfig(path string) (grc.Config, error) {
+	if path != "" {
+		return grc.LoadConfigFile(path)
+	}
+	return grc.ConfigFromEnv()
+}
+
+// run executes the command of args on cache, reading its input from r and writing its output to w
+func run(ctx context.Context, cache *grc.GormCache, limit int, args []string, r io.Reader, w io.Writer) error {
+	switch cmd, args := args[0], args[1:]; {
+	case cmd == "keys" && len(args) <= 1:
+		prefix := cache.Config().Prefix
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		return listKeys(ctx, cache, prefix, limit, w)
+	case cmd == "get" && len(args) == 1:
+		return getEntry(ctx, cache, args[0], w)
+	case cmd == "del" && len(args) > 0:
+		return cache.InvalidateKey(ctx, args...)
+	case cmd == "flush" && len(args) == 1:
+		if args[0] == "" {
+			return errors.New("flush needs a non-empty prefix")
+		}
+		flusher, ok := cache.Client().(grc.PrefixFlusher)
+		if !ok {
+			return grc.ErrNotSupported
+		}
+		return flusher.FlushPrefix(ctx, args[0])
+	case cmd == "export" && len(args) == 1:
+		n, err := grc.ExportEntries(ctx, cache.Client(), args[0], w)
+		fmt.Fprintf(os.Stderr, "exported %d entries\n", n)
+		return err
+	case cmd == "import" && len(args) == 0:
+		n, err := grc.ImportEntries(ctx, cache.Client(), r)
+		fmt.Fprintf(os.Stderr, "imported %d entries\n", n)
+		return err
+	default:
+		return fmt.Errorf("invalid command %q", append([]string{cmd}, args...))
+	}
+}
+
+// listKeys writes the keys under prefix with their ttls, sizes and payload summaries
+func listKeys(ctx context.Context, cache *grc.GormCache, prefix string, limit int, w io.Writer) error {
+	lister, ok := cache.Client().(grc.KeyLister)
+	if !ok {
+		return grc.ErrNotSupported
+	}
+	keys, err := lister.ListKeys(ctx, prefix, limit)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tTTL\tSIZE\tPAYLOAD")
+	for _, key := range keys {
+		info, err := cache.Inspect(ctx, key)
+		if errors.Is(err, grc.ErrCacheMiss) {
+			continue // expired since listed
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", key, formatTTL(info.TTL), info.Size, summary(info))
+	}
+	return tw.Flush()
+}
+
+// getEntry writes the ttl, size and indented payload of the entry of key
+func getEntry(ctx context.Context, cache *grc.GormCache, key string, w io.Writer) error {
+	info, err := cache.Inspect(ctx, key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "key:   %s\nttl:   %s\nsize:  %d\n", info.Key, formatTTL(info.TTL), info.Size)
+	if !info.FreshUntil.IsZero() {
+		fmt.Fprintf(w, "fresh: %s\n", info.FreshUntil.Format(time.RFC3339))
+	}
+	if info.Error != "" {
+		fmt.Fprintf(w, "error: %s\n", info.Error)
+		return nil
+	}
+	data, err := json.MarshalIndent(info.Value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl <= 0 {
+		return "-"
+	}
+	return ttl.Round(time.Second).String()
+}
+
+// summary returns a short description of the payload of an entry, e.g. the number of rows of a query result
+func summary(info grc.EntryInfo) string {
+	if info.Error != "" {
+		return "undecodable: " + info.Error
+	}
+	var s string
+	switch v := info.Value.(type) {
+	case nil:
+		return "empty"
+	case []interface{}:
+		s = fmt.Sprintf("%d rows", len(v))
+		if len(v) > 0 {
+			data, _ := json.Marshal(v[0])
+			s += " " + string(data)
+		}
+	default:
+		data, _ := json.Marshal(v)
+		s = string(data)
+	}
+	if len(s) > summaryLength {
+		s = s[:summaryLength-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRun tests listing, showing and deleting entries
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	client := grc.NewMemoryCache()
+	cache := grc.NewGormCache("grcctl", client, grc.CacheConfig{Prefix: "grc:"})
+	assert.NoError(t, client.Set(ctx, "grc:users:a", []byte(`[{"ID":1,"Name":"a"},{"ID":2,"Name":"b"}]`), time.Minute))
+	assert.NoError(t, client.Set(ctx, "grc:users:b", []byte(`[]`), 0))
+	assert.NoError(t, client.Set(ctx, "grc:orders:c", []byte(`not json`), time.Minute))
+	assert.NoError(t, client.Set(ctx, "other:d", []byte(`[]`), time.Minute))
+
+	var out bytes.Buffer
+	assert.NoError(t, run(ctx, cache, 0, []string{"keys"}, nil, &out))
+	assert.Contains(t, out.String(), "grc:users:a")
+	assert.Contains(t, out.String(), `2 rows {"ID":1,"Name":"a"}`)
+	assert.Contains(t, out.String(), "grc:orders:c")
+	assert.Contains(t, out.String(), "undecodable")
+	assert.NotContains(t, out.String(), "other:d")
+
+	out.Reset()
+	assert.NoError(t, run(ctx, cache, 0, []string{"get", "grc:users:a"}, nil, &out))
+	assert.Contains(t, out.String(), "ttl:   1m0s")
+	assert.Contains(t, out.String(), `"Name": "b"`)
+
+	assert.ErrorIs(t, run(ctx, cache, 0, []string{"get", "grc:missing"}, nil, &out), grc.ErrCacheMiss)
+	assert.Error(t, run(ctx, cache, 0, []string{"get"}, nil, &out))
+
+	out.Reset()
+	assert.NoError(t, run(ctx, cache, 0, []string{"export", "grc:users:"}, nil, &out))
+	other := grc.NewMemoryCache()
+	assert.NoError(t, run(ctx, grc.NewGormCache("grcctl_import", other, grc.CacheConfig{}), 0, []string{"import"}, &out, nil))
+	assert.Equal(t, []string{"grc:users:a", "grc:users:b"}, other.Keys(""))
+
+	assert.NoError(t, run(ctx, cache, 0, []string{"del", "grc:orders:c"}, nil, &out))
+	assert.NoError(t, run(ctx, cache, 0, []string{"flush", "grc:users:"}, nil, &out))
+	assert.Equal(t, []string{"other:d"}, client.Keys(""))
+}
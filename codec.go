@@ -0,0 +1,230 @@
+package grc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrChecksum is returned when a cached value does not match its checksum
+var ErrChecksum = errors.New("grc: checksum mismatch")
+
+// Codec is an interface for serialization of cached values
+type Codec interface {
+	Name() string // codec name used in statistics
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec serializes values with encoding/json, it is the default codec
+type JSONCodec struct{}
+
+// Name returns the codec name
+func (JSONCodec) Name() string {
+	return "json"
+}
+
+// Marshal encodes v to json bytes
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes json bytes into v
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec serializes values with encoding/gob, types stored in interface fields must be registered with gob.Register
+type GobCodec struct{}
+
+// Name returns the codec name
+func (GobCodec) Name() string {
+	return "gob"
+}
+
+// Marshal encodes v to gob bytes
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes gob bytes into v
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// MsgpackCodec serializes values with msgpack, which is faster and more compact than json
+type MsgpackCodec struct{}
+
+// Name returns the codec name
+func (MsgpackCodec) Name() string {
+	return "msgpack"
+}
+
+// Marshal encodes v to msgpack bytes
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal decodes msgpack bytes into v
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func codecOf(config CacheConfig) Codec {
+	if config.Codec == nil {
+		return JSONCodec{}
+	}
+	return config.Codec
+}
+
+// Stage is a transformation of encoded cache values, e.g. compression, encryption or checksum.
+// Stages in CacheConfig are applied in order after serialization on write, and in reverse order on read.
+type Stage interface {
+	Name() string                       // stage name used in statistics
+	Encode(data []byte) ([]byte, error) // transform data before writing to cache
+	Decode(data []byte) ([]byte, error) // reverse the transformation after reading from cache
+}
+
+// KeyedStage is a Stage whose transformation depends on the cache key of the value, e.g. encryption
+// authenticating the key, so a value copied to another key can not be read. Stages are called with
+// EncodeKey and DecodeKey instead of Encode and Decode whenever the key is known.
+type KeyedStage interface {
+	Stage
+	EncodeKey(key string, data []byte) ([]byte, error)
+	DecodeKey(key string, data []byte) ([]byte, error)
+}
+
+// encodeStage applies stage to the data of key
+func encodeStage(stage Stage, key string, data []byte) ([]byte, error) {
+	if keyed, ok := stage.(KeyedStage); ok {
+		return keyed.EncodeKey(key, data)
+	}
+	return stage.Encode(data)
+}
+
+// decodeStage reverses stage on the data of key
+func decodeStage(stage Stage, key string, data []byte) ([]byte, error) {
+	if keyed, ok := stage.(KeyedStage); ok {
+		return keyed.DecodeKey(key, data)
+	}
+	return stage.Decode(data)
+}
+
+// encode serializes the value v of key and applies all stages
+func (g *GormCache) encode(config CacheConfig, key string, v interface{}) ([]byte, error) {
+	codec := codecOf(config)
+	start := time.Now()
+	data, err := codec.Marshal(v)
+	g.stats.recordStage(codec.Name(), true, 0, len(data), time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stage := range config.Stages {
+		start = time.Now()
+		out, err := encodeStage(stage, key, data)
+		g.stats.recordStage(stage.Name(), true, len(data), len(out), time.Since(start), err)
+		if err != nil {
+			return nil, fmt.Errorf("%s encode: %w", stage.Name(), err)
+		}
+		data = out
+	}
+	return data, nil
+}
+
+// decode reverses all stages on the data of key and deserializes it into v
+func (g *GormCache) decode(config CacheConfig, key string, data []byte, v interface{}) error {
+	for i := len(config.Stages) - 1; i >= 0; i-- {
+		stage := config.Stages[i]
+		start := time.Now()
+		out, err := decodeStage(stage, key, data)
+		g.stats.recordStage(stage.Name(), false, len(data), len(out), time.Since(start), err)
+		if err != nil {
+			return fmt.Errorf("%s decode: %w", stage.Name(), err)
+		}
+		data = out
+	}
+
+	codec := codecOf(config)
+	start := time.Now()
+	err := codec.Unmarshal(data, v)
+	g.stats.recordStage(codec.Name(), false, len(data), 0, time.Since(start), err)
+	return err
+}
+
+// gzipStage compresses values with gzip
+type gzipStage struct {
+	level int
+}
+
+// NewGzipStage returns a Stage compressing values with gzip at level, e.g. gzip.DefaultCompression
+func NewGzipStage(level int) Stage {
+	return gzipStage{level: level}
+}
+
+func (s gzipStage) Name() string {
+	return "gzip"
+}
+
+func (s gzipStage) Encode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, s.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s gzipStage) Decode(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// checksumStage prepends a crc32 checksum to values and verifies it on read
+type checksumStage struct{}
+
+// NewChecksumStage returns a Stage detecting corrupted values with a crc32 checksum
+func NewChecksumStage() Stage {
+	return checksumStage{}
+}
+
+func (s checksumStage) Name() string {
+	return "checksum"
+}
+
+func (s checksumStage) Encode(data []byte) ([]byte, error) {
+	out := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(out, crc32.ChecksumIEEE(data))
+	copy(out[4:], data)
+	return out, nil
+}
+
+func (s checksumStage) Decode(data []byte) ([]byte, error) {
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != crc32.ChecksumIEEE(data[4:]) {
+		return nil, ErrChecksum
+	}
+	return data[4:], nil
+}
@@ -0,0 +1,53 @@
+package grc
+
+import (
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCodecStages tests encoding and decoding values through stages
+func TestCodecStages(t *testing.T) {
+	cache := NewGormCache("my_cache", nil, CacheConfig{})
+	config := CacheConfig{
+		Stages: []Stage{NewGzipStage(gzip.DefaultCompression), NewChecksumStage()},
+	}
+	users := []TestUser{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}}
+
+	data, err := cache.encode(config, "key", users)
+	assert.NoError(t, err)
+
+	var decoded []TestUser
+	assert.NoError(t, cache.decode(config, "key", data, &decoded))
+	assert.Equal(t, users, decoded)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Stages["gzip"].Encodes)
+	assert.Equal(t, int64(1), stats.Stages["gzip"].Decodes)
+	assert.Equal(t, int64(1), stats.Stages["checksum"].Encodes)
+	assert.Equal(t, int64(1), stats.Stages["json"].Decodes)
+
+	// corrupt the value
+	data[len(data)-1] ^= 0xff
+	assert.ErrorIs(t, cache.decode(config, "key", data, &decoded), ErrChecksum)
+	assert.Equal(t, int64(1), cache.Stats().Stages["checksum"].Errors)
+}
+
+// TestCodecs tests round trips of the built-in codecs
+func TestCodecs(t *testing.T) {
+	cache := NewGormCache("my_cache", nil, CacheConfig{})
+	users := []TestUser{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}}
+
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}, MsgpackCodec{}} {
+		config := CacheConfig{Codec: codec}
+
+		data, err := cache.encode(config, "key", &users)
+		assert.NoError(t, err, codec.Name())
+
+		var decoded []TestUser
+		assert.NoError(t, cache.decode(config, "key", data, &decoded), codec.Name())
+		assert.Equal(t, users, decoded, codec.Name())
+		assert.Equal(t, int64(1), cache.Stats().Stages[codec.Name()].Decodes, codec.Name())
+	}
+}
@@ -0,0 +1,192 @@
+package grc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BackendRedis is the backend name of RedisClient
+const BackendRedis = "redis"
+
+// Config is a struct for cache options and backend selection built by the config loaders
+type Config struct {
+	Cache   CacheConfig // cache options
+	Enabled bool        // whether caching is enabled
+	Backend string      // cache backend name
+	Redis   RedisConfig // redis options, used by the redis backend
+}
+
+// ConfigFromEnv loads cache options and backend selection from environment variables:
+//
+//	GRC_TTL                  cache expiration time, e.g. 60s or 60
+//	GRC_PREFIX               cache key prefix
+//	GRC_ENABLED              whether caching is enabled, default true
+//	GRC_BACKEND              cache backend, default redis
+//	GRC_REDIS_ADDR           redis address, default localhost:6379
+//	GRC_REDIS_USERNAME       redis acl username
+//	GRC_REDIS_PASSWORD       redis password
+//	GRC_REDIS_DB             redis database, default 0
+//	GRC_REDIS_TLS            whether to connect to redis with tls, default false
+//	GRC_REDIS_DIAL_TIMEOUT   timeout of connecting to redis, default 5s
+//	GRC_REDIS_READ_TIMEOUT   timeout of reading redis replies, default 3s
+//	GRC_REDIS_WRITE_TIMEOUT  timeout of writing redis commands, default the read timeout
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Enabled: true,
+		Backend: BackendRedis,
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
+		},
+	}
+
+	var err error
+	if v, ok := os.LookupEnv("GRC_TTL"); ok {
+		if config.Cache.TTL, err = ParseTTL(v); err != nil {
+			return config, fmt.Errorf("invalid GRC_TTL: %w", err)
+		}
+	}
+	if v, ok := os.LookupEnv("GRC_PREFIX"); ok {
+		config.Cache.Prefix = v
+	}
+	if v, ok := os.LookupEnv("GRC_ENABLED"); ok {
+		if config.Enabled, err = strconv.ParseBool(v); err != nil {
+			return config, fmt.Errorf("invalid GRC_ENABLED %q: %w", v, err)
+		}
+	}
+	if v, ok := os.LookupEnv("GRC_BACKEND"); ok {
+		config.Backend = v
+	}
+	if v, ok := os.LookupEnv("GRC_REDIS_ADDR"); ok {
+		config.Redis.Addr = v
+	}
+	if v, ok := os.LookupEnv("GRC_REDIS_USERNAME"); ok {
+		config.Redis.Username = v
+	}
+	if v, ok := os.LookupEnv("GRC_REDIS_PASSWORD"); ok {
+		config.Redis.Password = v
+	}
+	if v, ok := os.LookupEnv("GRC_REDIS_DB"); ok {
+		if config.Redis.DB, err = strconv.Atoi(v); err != nil {
+			return config, fmt.Errorf("invalid GRC_REDIS_DB %q: %w", v, err)
+		}
+	}
+	if v, ok := os.LookupEnv("GRC_REDIS_TLS"); ok {
+		if config.Redis.TLS, err = strconv.ParseBool(v); err != nil {
+			return config, fmt.Errorf("invalid GRC_REDIS_TLS %q: %w", v, err)
+		}
+	}
+	for name, timeout := range map[string]*time.Duration{
+		"GRC_REDIS_DIAL_TIMEOUT":  &config.Redis.DialTimeout,
+		"GRC_REDIS_READ_TIMEOUT":  &config.Redis.ReadTimeout,
+		"GRC_REDIS_WRITE_TIMEOUT": &config.Redis.WriteTimeout,
+	} {
+		if v, ok := os.LookupEnv(name); ok {
+			if *timeout, err = ParseTTL(v); err != nil {
+				return config, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+	return config, nil
+}
+
+// NewClient returns a new CacheClient of the selected backend
+func (c Config) NewClient() (CacheClient, error) {
+	switch c.Backend {
+	case BackendRedis:
+		return NewRedisClientFromConfig(c.Redis), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", c.Backend)
+	}
+}
+
+// NewGormCache returns a new GormCache instance using the selected backend, disabled if caching is not enabled
+func (c Config) NewGormCache(name string) (*GormCache, error) {
+	client, err := c.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	cache := NewGormCache(name, client, c.Cache)
+	if !c.Enabled {
+		cache.Disable()
+	}
+	return cache, nil
+}
+
+// FileConfig is a struct for options loaded from a yaml or json config file,
+// it can be embedded into the config struct of an application
+type FileConfig struct {
+	TTL           string            `json:"ttl" yaml:"ttl"`                       // cache expiration time, e.g. 60s or 60
+	Prefix        string            `json:"prefix" yaml:"prefix"`                 // cache key prefix
+	Enabled       *bool             `json:"enabled" yaml:"enabled"`               // whether caching is enabled, default true
+	Backend       string            `json:"backend" yaml:"backend"`               // cache backend, default redis
+	Redis         RedisConfig       `json:"redis" yaml:"redis"`                   // redis options
+	TTLByTable    map[string]string `json:"ttl_by_table" yaml:"ttl_by_table"`     // cache expiration time by table name
+	Tables        []string          `json:"tables" yaml:"tables"`                 // only cache queries on these tables if not empty
+	ExcludeTables []string          `json:"exclude_tables" yaml:"exclude_tables"` // never cache queries on these tables
+}
+
+// Config builds cache options and backend selection from the file config
+func (f FileConfig) Config() (Config, error) {
+	config := Config{
+		Cache: CacheConfig{
+			Prefix:        f.Prefix,
+			Tables:        f.Tables,
+			ExcludeTables: f.ExcludeTables,
+		},
+		Enabled: true,
+		Backend: f.Backend,
+		Redis:   f.Redis,
+	}
+	if f.Enabled != nil {
+		config.Enabled = *f.Enabled
+	}
+	if config.Backend == "" {
+		config.Backend = BackendRedis
+	}
+	if config.Redis.Addr == "" {
+		config.Redis.Addr = "localhost:6379"
+	}
+
+	var err error
+	if f.TTL != "" {
+		if config.Cache.TTL, err = ParseTTL(f.TTL); err != nil {
+			return config, err
+		}
+	}
+	if len(f.TTLByTable) > 0 {
+		config.Cache.TTLByTable = make(map[string]time.Duration, len(f.TTLByTable))
+		for table, v := range f.TTLByTable {
+			if config.Cache.TTLByTable[table], err = ParseTTL(v); err != nil {
+				return config, fmt.Errorf("table %s: %w", table, err)
+			}
+		}
+	}
+	return config, nil
+}
+
+// LoadConfigFile loads cache options and backend selection from a config file,
+// files with .yaml or .yml extension are decoded as yaml, others as json
+func LoadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var f FileConfig
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &f)
+	default:
+		err = json.Unmarshal(data, &f)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("decode config file %s: %w", path, err)
+	}
+	return f.Config()
+}
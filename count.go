@@ -0,0 +1,43 @@
+package grc
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// countEntry is the cached value of a Count query, Count overrides the result with
+// rows affected when the query has a GROUP BY clause, so both are cached
+type countEntry struct {
+	Count        int64
+	RowsAffected int64
+}
+
+// countDest returns the destination of a Count query
+func countDest(db *gorm.DB) (*int64, bool) {
+	count, ok := db.Statement.Dest.(*int64)
+	if !ok {
+		return nil, false
+	}
+	c, ok := db.Statement.Clauses["SELECT"]
+	if !ok {
+		return nil, false
+	}
+	var sql string
+	switch e := c.Expression.(type) {
+	case clause.Expr:
+		sql = e.SQL
+	case clause.Select:
+		if expr, ok := e.Expression.(clause.Expr); ok {
+			sql = expr.SQL
+		} else if len(e.Columns) == 1 && e.Columns[0].Raw {
+			sql = e.Columns[0].Name // count expression selected with Select
+		}
+	}
+	return count, isCountSQL(sql)
+}
+
+func isCountSQL(sql string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(sql)), "count(")
+}
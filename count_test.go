@@ -0,0 +1,57 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+// TestCacheCount tests caching Count queries
+func TestCacheCount(t *testing.T) {
+	cache := NewGormCache("count_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "count:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	for i := 0; i < 2; i++ {
+		var count int64
+		assert.NoError(t, db.Session(&gorm.Session{Context: ctx}).Model(&TestUser{}).Where("id > ?", 10).Count(&count).Error)
+		assert.Equal(t, int64(userCount-10), count)
+
+		// group by counts groups
+		var groups int64
+		assert.NoError(t, db.Session(&gorm.Session{Context: ctx}).Model(&TestUser{}).Group("name").Count(&groups).Error)
+		assert.Equal(t, int64(userCount), groups)
+	}
+
+	keys, err := rdb.Keys(context.Background(), "count:test_users:count:*").Result()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
+
+// TestCountDest tests detecting Count queries
+func TestCountDest(t *testing.T) {
+	dryDB, err := gorm.Open(postgres.Open(""), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	assert.NoError(t, err)
+
+	var detected []bool
+	err = dryDB.Callback().Query().Before("gorm:query").Register("test:count", func(db *gorm.DB) {
+		callbacks.BuildQuerySQL(db)
+		_, ok := countDest(db)
+		detected = append(detected, ok)
+	})
+	assert.NoError(t, err)
+
+	var count, id int64
+	dryDB.Model(&TestUser{}).Count(&count)
+	dryDB.Model(&TestUser{}).Select("count(distinct(name))").Count(&count)
+	dryDB.Model(&TestUser{}).Select("id").Find(&id)
+	assert.Equal(t, []bool{true, true, false}, detected)
+}
@@ -0,0 +1,80 @@
+package grc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DumpEntry is a cache entry in a dump written by ExportEntries, dumps are json lines with one entry per line
+type DumpEntry struct {
+	Key   string `json:"key"`
+	TTL   int64  `json:"ttl_ms"` // remaining ttl in milliseconds at export, 0 without expiration or if the cache client can not tell
+	Value []byte `json:"value"`  // stored value as is, base64 in json
+}
+
+// ExportEntries writes all entries of client under prefix to w as a dump, e.g. to migrate a cache to another redis
+// or seed a staging cache with ImportEntries, and returns the number of entries written.
+// The client must implement KeyLister, remaining ttls are exported if it implements CacheClientExt.
+// Entries with less than a millisecond left are skipped, as their ttl would be written as 0 and imported without expiration.
+func ExportEntries(ctx context.Context, client CacheClient, prefix string, w io.Writer) (int, error) {
+	lister, ok := client.(KeyLister)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	keys, err := lister.ListKeys(ctx, prefix, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	enc := json.NewEncoder(w)
+	n := 0
+	for _, key := range keys {
+		value, ttl, err := getWithTTL(ctx, client, key)
+		if isCacheMiss(err) || (err == nil && value == nil) {
+			continue // expired since listed
+		}
+		if err != nil {
+			return n, err
+		}
+		if ttl > 0 && ttl < time.Millisecond {
+			continue // expires before it could be imported
+		}
+		data, err := ValueBytes(value)
+		if err != nil {
+			return n, fmt.Errorf("key %s: %w", key, err)
+		}
+		if err := enc.Encode(DumpEntry{Key: key, TTL: ttl.Milliseconds(), Value: data}); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ImportEntries sets the entries of a dump read from r to client with their remaining ttls at export,
+// and returns the number of entries set
+func ImportEntries(ctx context.Context, client CacheClient, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	n := 0
+	for {
+		var entry DumpEntry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("decode dump entry %d: %w", n+1, err)
+		}
+		if entry.Key == "" {
+			return n, fmt.Errorf("dump entry %d: missing key", n+1)
+		}
+		if err := client.Set(ctx, entry.Key, entry.Value, time.Duration(entry.TTL)*time.Millisecond); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
@@ -0,0 +1,74 @@
+package grc
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExportImportEntries tests migrating entries under a prefix between cache clients with a dump
+func TestExportImportEntries(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryCache()
+	assert.NoError(t, src.Set(ctx, "grc:a", []byte(`[{"ID":1}]`), time.Minute))
+	assert.NoError(t, src.Set(ctx, "grc:b", []byte{0, 1, 2}, 0))
+	assert.NoError(t, src.Set(ctx, "other:c", []byte(`[]`), time.Minute))
+
+	var buf bytes.Buffer
+	n, err := ExportEntries(ctx, src, "grc:", &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	dst := NewMemoryCache()
+	n, err = ImportEntries(ctx, dst, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"grc:a", "grc:b"}, dst.Keys(""))
+
+	value, ttl, err := dst.GetWithTTL(ctx, "grc:a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`[{"ID":1}]`), value)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+	value, ttl, err = dst.GetWithTTL(ctx, "grc:b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, value)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	_, err = ImportEntries(ctx, dst, strings.NewReader(`{"ttl_ms":1}`))
+	assert.Error(t, err)
+	_, err = ExportEntries(ctx, newMapClient(), "", &buf)
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+// expiringCache is a MemoryCache reporting a remaining ttl below a millisecond for the key "expiring"
+type expiringCache struct {
+	*MemoryCache
+}
+
+func (c expiringCache) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	value, ttl, err := c.MemoryCache.GetWithTTL(ctx, key)
+	if key == "expiring" {
+		ttl = 500 * time.Microsecond
+	}
+	return value, ttl, err
+}
+
+// TestExportEntriesExpiring tests skipping entries with less than a millisecond left, which would be imported without expiration
+func TestExportEntriesExpiring(t *testing.T) {
+	ctx := context.Background()
+	src := expiringCache{NewMemoryCache()}
+	assert.NoError(t, src.Set(ctx, "expiring", []byte("A"), time.Minute))
+	assert.NoError(t, src.Set(ctx, "kept", []byte("B"), time.Minute))
+
+	var buf bytes.Buffer
+	n, err := ExportEntries(ctx, src, "", &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NotContains(t, buf.String(), "expiring")
+	assert.Contains(t, buf.String(), `"key":"kept"`)
+}
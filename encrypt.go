@@ -0,0 +1,138 @@
+package grc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrCiphertext is returned when a cached value cannot be decrypted
+var ErrCiphertext = errors.New("grc: invalid ciphertext")
+
+// KeyProvider provides encryption keys of 16, 24 or 32 bytes for AES-128, AES-192 or AES-256.
+// Keys are identified by id, so values encrypted with a previous key can still be decrypted after rotation.
+type KeyProvider interface {
+	CurrentKey() (id string, key []byte, err error) // key used to encrypt new values
+	Key(id string) ([]byte, error)                  // key used to decrypt values encrypted with id
+}
+
+// staticKey is a KeyProvider of a single key
+type staticKey []byte
+
+// StaticKey returns a KeyProvider of a single key
+func StaticKey(key []byte) KeyProvider {
+	return staticKey(key)
+}
+
+func (k staticKey) CurrentKey() (string, []byte, error) {
+	return "", k, nil
+}
+
+func (k staticKey) Key(id string) ([]byte, error) {
+	if id != "" {
+		return nil, fmt.Errorf("unknown key id %q", id)
+	}
+	return k, nil
+}
+
+// aesGCMStage encrypts values with AES-GCM, the encrypted value is laid out as
+// key id length (1 byte) | key id | nonce | ciphertext.
+// The key id and the cache key are authenticated as additional data, so a value copied to another key fails to decrypt.
+type aesGCMStage struct {
+	keys  KeyProvider
+	aeads sync.Map // key id => cipher.AEAD
+}
+
+// NewAESGCMStage returns a KeyedStage encrypting values with AES-GCM using keys from provider,
+// values are bound to their cache key
+func NewAESGCMStage(keys KeyProvider) Stage {
+	return &aesGCMStage{keys: keys}
+}
+
+func (s *aesGCMStage) Name() string {
+	return "aes-gcm"
+}
+
+func (s *aesGCMStage) aead(id string, key []byte) (cipher.AEAD, error) {
+	if aead, ok := s.aeads.Load(id); ok {
+		return aead.(cipher.AEAD), nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s.aeads.Store(id, aead)
+	return aead, nil
+}
+
+// additionalData returns the additional data of a value of key with the given header
+func additionalData(header []byte, key string) []byte {
+	return append(append(make([]byte, 0, len(header)+len(key)), header...), key...)
+}
+
+// Encode encrypts data without binding it to a cache key
+func (s *aesGCMStage) Encode(data []byte) ([]byte, error) {
+	return s.EncodeKey("", data)
+}
+
+// Decode decrypts data not bound to a cache key
+func (s *aesGCMStage) Decode(data []byte) ([]byte, error) {
+	return s.DecodeKey("", data)
+}
+
+func (s *aesGCMStage) EncodeKey(cacheKey string, data []byte) ([]byte, error) {
+	id, key, err := s.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("key id %q too long", id)
+	}
+	aead, err := s.aead(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 1+len(id)+aead.NonceSize(), 1+len(id)+aead.NonceSize()+len(data)+aead.Overhead())
+	out[0] = byte(len(id))
+	copy(out[1:], id)
+	nonce := out[1+len(id):]
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, data, additionalData(out[:1+len(id)], cacheKey)), nil
+}
+
+func (s *aesGCMStage) DecodeKey(cacheKey string, data []byte) ([]byte, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, ErrCiphertext
+	}
+	header := data[:1+data[0]]
+	id := string(header[1:])
+	data = data[len(header):]
+
+	key, err := s.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := s.aead(id, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrCiphertext
+	}
+	out, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData(header, cacheKey))
+	if err != nil {
+		return nil, ErrCiphertext
+	}
+	return out, nil
+}
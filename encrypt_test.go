@@ -0,0 +1,105 @@
+package grc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rotatingKeys is a KeyProvider with a current key and previous keys
+type rotatingKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (k rotatingKeys) CurrentKey() (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+func (k rotatingKeys) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", id)
+	}
+	return key, nil
+}
+
+// TestAESGCMStage tests encrypting and decrypting values
+func TestAESGCMStage(t *testing.T) {
+	plain := []byte(`[{"ID":1,"Name":"A"}]`)
+	stage := NewAESGCMStage(StaticKey(bytes.Repeat([]byte{1}, 32)))
+
+	encrypted, err := stage.Encode(plain)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "Name")
+
+	decrypted, err := stage.Decode(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, decrypted)
+
+	// tampered value
+	encrypted[len(encrypted)-1] ^= 0xff
+	_, err = stage.Decode(encrypted)
+	assert.ErrorIs(t, err, ErrCiphertext)
+
+	// wrong key
+	other := NewAESGCMStage(StaticKey(bytes.Repeat([]byte{2}, 32)))
+	encrypted, err = other.Encode(plain)
+	assert.NoError(t, err)
+	_, err = stage.Decode(encrypted)
+	assert.ErrorIs(t, err, ErrCiphertext)
+}
+
+// TestAESGCMStageRotation tests decrypting values encrypted with a previous key
+func TestAESGCMStageRotation(t *testing.T) {
+	plain := []byte("value")
+	keys := rotatingKeys{current: "v1", keys: map[string][]byte{
+		"v1": bytes.Repeat([]byte{1}, 16),
+		"v2": bytes.Repeat([]byte{2}, 16),
+	}}
+
+	encrypted, err := NewAESGCMStage(keys).Encode(plain)
+	assert.NoError(t, err)
+
+	keys.current = "v2"
+	decrypted, err := NewAESGCMStage(keys).Decode(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, decrypted)
+}
+
+// TestAESGCMStageKey tests that encrypted values are bound to their cache key
+func TestAESGCMStageKey(t *testing.T) {
+	plain := []byte("value")
+	stage := NewAESGCMStage(StaticKey(bytes.Repeat([]byte{1}, 32))).(KeyedStage)
+
+	encrypted, err := stage.EncodeKey("users:1", plain)
+	assert.NoError(t, err)
+	decrypted, err := stage.DecodeKey("users:1", encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, decrypted)
+
+	// value copied to another key
+	_, err = stage.DecodeKey("users:2", encrypted)
+	assert.ErrorIs(t, err, ErrCiphertext)
+	_, err = stage.Decode(encrypted)
+	assert.ErrorIs(t, err, ErrCiphertext)
+
+	// the same through the WithStage middleware
+	ctx := context.Background()
+	memory := NewMemoryCache()
+	client := Chain(memory, WithStage(stage))
+	assert.NoError(t, client.Set(ctx, "users:1", plain, time.Minute))
+	value, err := client.Get(ctx, "users:1")
+	assert.NoError(t, err)
+	assert.Equal(t, plain, value)
+
+	raw, err := memory.Get(ctx, "users:1")
+	assert.NoError(t, err)
+	assert.NoError(t, memory.Set(ctx, "users:2", raw, time.Minute))
+	_, err = client.Get(ctx, "users:2")
+	assert.ErrorIs(t, err, ErrCiphertext)
+}
@@ -0,0 +1,26 @@
+package grc
+
+import "gorm.io/gorm"
+
+// Explain reports the cache key of the query of db, whether it would be cached with the current config and
+// the reason if not. The query must be built, e.g. executed in a DryRun session:
+//
+//	stmt := db.Session(&gorm.Session{DryRun: true}).Clauses(grc.Cache{}).Where("id = ?", 1).Find(&users)
+//	key, cacheable, reason := cache.Explain(stmt)
+//
+// Results can still be skipped when they are written, e.g. empty results without CacheEmptyResults
+// or results exceeding MaxValueBytes.
+func (g *GormCache) Explain(db *gorm.DB) (key string, cacheable bool, reason string) {
+	if db.Statement.SQL.Len() == 0 {
+		return "", false, "query not built, execute it in a DryRun session"
+	}
+	config := g.Config()
+	key = g.cacheKey(db, config)
+	if cacheable, reason = g.cacheDecision(db, config); !cacheable {
+		return key, false, reason
+	}
+	if isVolatileSQL(db.Statement.SQL.String(), config) {
+		return key, false, "volatile sql function"
+	}
+	return key, true, ""
+}
@@ -0,0 +1,44 @@
+package grc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TestExplain tests explaining the key and cache decision of queries built in DryRun sessions
+func TestExplain(t *testing.T) {
+	client := newMapClient()
+	cache := NewGormCache("explain_cache", client, CacheConfig{TTL: time.Minute, Prefix: "explain:"})
+	assert.NoError(t, db.Use(cache))
+	dry := db.Session(&gorm.Session{DryRun: true})
+
+	var users []TestUser
+	key, cacheable, reason := cache.Explain(Session(dry).Where("id > ?", 90).Find(&users))
+	assert.True(t, cacheable)
+	assert.Empty(t, reason)
+
+	// the key of the query executed
+	assert.NoError(t, Session(db).Where("id > ?", 90).Find(&users).Error)
+	assert.Contains(t, client.values, key)
+
+	_, cacheable, reason = cache.Explain(dry.Where("id > ?", 90).Find(&users))
+	assert.False(t, cacheable)
+	assert.Equal(t, "cache not used by the query", reason)
+
+	_, cacheable, reason = cache.Explain(Session(dry).Clauses(clause.Locking{Strength: "UPDATE"}).Find(&users))
+	assert.False(t, cacheable)
+	assert.Equal(t, "locking read", reason)
+
+	var count int64
+	_, cacheable, reason = cache.Explain(Session(dry).Raw("SELECT COUNT(*) FROM test_users").Scan(&count))
+	assert.False(t, cacheable)
+	assert.Equal(t, "raw sql not cached without CacheRaw", reason)
+
+	_, cacheable, reason = cache.Explain(Session(db).Where("id > ?", 90))
+	assert.False(t, cacheable)
+	assert.Equal(t, "query not built, execute it in a DryRun session", reason)
+}
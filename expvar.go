@@ -0,0 +1,58 @@
+package grc
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+)
+
+// expvarName is the name of the expvar variable holding the statistics of published caches
+const expvarName = "grc"
+
+var (
+	expvarOnce   sync.Once
+	expvarCaches sync.Map // cache name -> *GormCache
+)
+
+// statsJSON is the json representation of cache statistics
+type statsJSON struct {
+	Stats
+	HitRatio float64
+}
+
+// statsByName returns the json representation of the statistics of caches by name
+func statsByName(caches []*GormCache) map[string]statsJSON {
+	stats := make(map[string]statsJSON, len(caches))
+	for _, cache := range caches {
+		s := cache.Stats()
+		stats[cache.Name()] = statsJSON{Stats: s, HitRatio: s.HitRatio()}
+	}
+	return stats
+}
+
+// PublishExpvar publishes the statistics of the cache under its name in the expvar variable "grc",
+// which is served on /debug/vars by the expvar package. A cache published later under the same name replaces it.
+func (g *GormCache) PublishExpvar() {
+	expvarOnce.Do(func() {
+		expvar.Publish(expvarName, expvar.Func(func() interface{} {
+			var caches []*GormCache
+			expvarCaches.Range(func(_, cache interface{}) bool {
+				caches = append(caches, cache.(*GormCache))
+				return true
+			})
+			return statsByName(caches)
+		}))
+	})
+	expvarCaches.Store(g.name, g)
+}
+
+// StatsHandler returns an http.Handler serving the statistics of caches by name as json
+func StatsHandler(caches ...*GormCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(statsByName(caches)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package grc
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPublishExpvar tests publishing statistics by cache name in expvar
+func TestPublishExpvar(t *testing.T) {
+	users := NewGormCache("expvar_users", nil, CacheConfig{})
+	users.stats.recordHit()
+	users.stats.recordMiss()
+	users.PublishExpvar()
+	NewGormCache("expvar_orders", nil, CacheConfig{}).PublishExpvar()
+
+	var stats map[string]struct {
+		Hits     int64
+		HitRatio float64
+	}
+	assert.NoError(t, json.Unmarshal([]byte(expvar.Get("grc").String()), &stats))
+	assert.Equal(t, int64(1), stats["expvar_users"].Hits)
+	assert.Equal(t, 0.5, stats["expvar_users"].HitRatio)
+	assert.Contains(t, stats, "expvar_orders")
+}
+
+// TestStatsHandler tests serving statistics by cache name as json
+func TestStatsHandler(t *testing.T) {
+	cache := NewGormCache("my_cache", nil, CacheConfig{})
+	cache.stats.recordSet("users", 10)
+
+	w := httptest.NewRecorder()
+	StatsHandler(cache).ServeHTTP(w, httptest.NewRequest("GET", "/debug/grc", nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var stats map[string]Stats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, int64(1), stats["my_cache"].Sets)
+	assert.Equal(t, TableStats{Sets: 1, Bytes: 10}, stats["my_cache"].Tables["users"])
+}
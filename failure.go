@@ -0,0 +1,80 @@
+package grc
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// FailurePolicy decides what happens to a query when the cache layer fails
+type FailurePolicy int
+
+const (
+	FailOpen   FailurePolicy = iota // log the error and query the database as if caching was disabled
+	FailClosed                      // fail the query with the error
+)
+
+// ErrorClass is the class of a cache layer error, used to override the FailurePolicy by class
+type ErrorClass int
+
+const (
+	ErrorClassBackend ErrorClass = iota // a call of the cache client failed, e.g. a timeout or ErrCircuitOpen
+	ErrorClassDecode                    // a cached value can not be decoded, e.g. a checksum or decryption failure
+	ErrorClassEncode                    // a query result can not be encoded
+)
+
+// classError is an error of a class other than ErrorClassBackend
+type classError struct {
+	class ErrorClass
+	err   error
+}
+
+func (e *classError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classError) Unwrap() error {
+	return e.err
+}
+
+// classify marks a non-nil err as an error of class
+func classify(class ErrorClass, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classError{class: class, err: err}
+}
+
+// ErrorClassOf returns the class of a cache layer error, errors of the cache client are ErrorClassBackend
+func ErrorClassOf(err error) ErrorClass {
+	var classErr *classError
+	if errors.As(err, &classErr) {
+		return classErr.class
+	}
+	return ErrorClassBackend
+}
+
+// failurePolicy returns the FailurePolicy of err, the one of its class in FailurePolicyByClass if set
+func failurePolicy(config CacheConfig, err error) FailurePolicy {
+	if policy, ok := config.FailurePolicyByClass[ErrorClassOf(err)]; ok {
+		return policy
+	}
+	return config.FailurePolicy
+}
+
+// cacheFailed records and logs a failed cache operation, calls OnError and fails the query if the policy
+// of the error is FailClosed. It reports whether the query must stop.
+func (g *GormCache) cacheFailed(db *gorm.DB, config CacheConfig, event HookEvent) bool {
+	g.stats.recordError()
+	if config.Logger != nil {
+		logEvent(db, config, config.LogLevels.Error.or(LogError), "cache "+event.Op+" failed", event)
+	} else {
+		logMessage(db.Statement.Context, config, LogError, event.Op+" cache failed", event.Err)
+	}
+	callHook(db, config.OnError, event)
+	if failurePolicy(config, event.Err) == FailClosed {
+		db.AddError(event.Err)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestErrorClassOf tests classifying cache layer errors
+func TestErrorClassOf(t *testing.T) {
+	assert.Equal(t, ErrorClassBackend, ErrorClassOf(errors.New("i/o timeout")))
+	assert.Equal(t, ErrorClassDecode, ErrorClassOf(classify(ErrorClassDecode, ErrChecksum)))
+	assert.Equal(t, ErrorClassEncode, ErrorClassOf(fmt.Errorf("set: %w", classify(ErrorClassEncode, errors.New("unsupported type")))))
+	assert.ErrorIs(t, classify(ErrorClassDecode, ErrChecksum), ErrChecksum)
+	assert.Nil(t, classify(ErrorClassDecode, nil))
+
+	config := CacheConfig{FailurePolicyByClass: map[ErrorClass]FailurePolicy{ErrorClassDecode: FailClosed}}
+	assert.Equal(t, FailOpen, failurePolicy(config, errors.New("i/o timeout")))
+	assert.Equal(t, FailClosed, failurePolicy(config, classify(ErrorClassDecode, ErrChecksum)))
+}
+
+// TestFailurePolicy tests querying the database or failing the query when the cache layer fails
+func TestFailurePolicy(t *testing.T) {
+	ctx := context.Background()
+	client := &downCache{MemoryCache: NewMemoryCache(), down: 1}
+	defer client.Close()
+	cache := NewGormCache("failure_cache", client, CacheConfig{TTL: time.Minute})
+	assert.NoError(t, db.Use(cache))
+
+	// fail open, the query goes to the database
+	var user TestUser
+	assert.NoError(t, Session(db).Where("id = ?", 1).First(&user).Error)
+	assert.Equal(t, 1, user.ID)
+	assert.Equal(t, int64(2), cache.Stats().Errors) // load and set
+
+	cache.UpdateConfig(CacheConfig{TTL: time.Minute, FailurePolicy: FailClosed})
+	user = TestUser{}
+	assert.ErrorIs(t, Session(db).Where("id = ?", 1).First(&user).Error, errDown)
+	assert.Equal(t, 0, user.ID)
+
+	// fail closed on undecodable values only
+	atomic.StoreInt32(&client.down, 0)
+	cache.UpdateConfig(CacheConfig{TTL: time.Minute, FailurePolicyByClass: map[ErrorClass]FailurePolicy{ErrorClassDecode: FailClosed}})
+	assert.NoError(t, Session(db).Where("id = ?", 1).First(&user).Error)
+	for _, key := range client.Keys("") {
+		assert.NoError(t, client.Set(ctx, key, "corrupt", time.Minute))
+	}
+	user = TestUser{}
+	err := Session(db).Where("id = ?", 1).First(&user).Error
+	assert.Error(t, err)
+	assert.Equal(t, ErrorClassDecode, ErrorClassOf(err))
+}
@@ -0,0 +1,285 @@
+package grc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FallbackCache is a cache client using a primary cache, e.g. redis, and a local fallback cache, e.g. a MemoryCache,
+// while the primary cache fails, so caching stays available during outages of the primary cache.
+//
+// Deletions and prefix flushes failing on the primary cache are replayed when it recovers, until then the keys
+// they cover are read from the fallback cache. With backfill, entries set to the fallback cache during the outage
+// are written to the primary cache when it recovers, otherwise they are dropped.
+type FallbackCache struct {
+	primary  CacheClient
+	fallback CacheClient
+	backfill bool
+
+	mu        sync.Mutex
+	degraded  bool                 // the last call of the primary cache failed
+	replaying bool                 // pending changes are being replayed to the primary cache
+	dels      map[string]struct{}  // keys to delete from the primary cache
+	prefixes  []string             // prefixes to flush from the primary cache
+	written   map[string]time.Time // keys to backfill to the primary cache with their expiration, zero without
+}
+
+// NewFallbackCache returns a new FallbackCache instance, entries set to fallback during an outage of primary
+// are written to primary when it recovers if backfill is set
+func NewFallbackCache(primary CacheClient, fallback CacheClient, backfill bool) *FallbackCache {
+	return &FallbackCache{
+		primary:  primary,
+		fallback: fallback,
+		backfill: backfill,
+		dels:     make(map[string]struct{}),
+		written:  make(map[string]time.Time),
+	}
+}
+
+// Get gets value from the primary cache, and from the fallback cache if the primary cache fails
+// or the key has pending changes
+func (f *FallbackCache) Get(ctx context.Context, key string) (interface{}, error) {
+	if f.pending(key) {
+		return f.fallback.Get(ctx, key)
+	}
+	value, err := f.primary.Get(ctx, key)
+	if err != nil && !isCacheMiss(err) {
+		f.failed()
+		return f.fallback.Get(ctx, key)
+	}
+	f.succeeded()
+	return value, err
+}
+
+// Set sets value to the primary cache, and to the fallback cache if the primary cache fails
+func (f *FallbackCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := f.primary.Set(ctx, key, value, ttl); err == nil {
+		f.mu.Lock()
+		// the value replaces pending changes, a backfill would overwrite it
+		delete(f.dels, key)
+		delete(f.written, key)
+		f.mu.Unlock()
+		f.succeeded()
+		return nil
+	}
+	f.failed()
+
+	if err := f.fallback.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	if f.backfill {
+		var expireAt time.Time
+		if ttl > 0 {
+			expireAt = time.Now().Add(ttl)
+		}
+		f.mu.Lock()
+		f.written[key] = expireAt
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// Del deletes keys from the fallback cache and the primary cache, returns ErrNotSupported if the primary cache
+// is not a KeyDeleter. Deletions failing on the primary cache are replayed when it recovers.
+func (f *FallbackCache) Del(ctx context.Context, keys ...string) error {
+	deleter, ok := f.primary.(KeyDeleter)
+	if !ok {
+		return ErrNotSupported
+	}
+	if local, ok := f.fallback.(KeyDeleter); ok {
+		if err := local.Del(ctx, keys...); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	for _, key := range keys {
+		delete(f.written, key)
+	}
+	f.mu.Unlock()
+	if err := deleter.Del(ctx, keys...); err != nil {
+		f.failed()
+		f.mu.Lock()
+		for _, key := range keys {
+			f.dels[key] = struct{}{}
+		}
+		f.mu.Unlock()
+		return nil
+	}
+	f.succeeded()
+	return nil
+}
+
+// FlushPrefix deletes all keys under prefix from the fallback cache and the primary cache, returns ErrNotSupported
+// if the primary cache is not a PrefixFlusher. Flushes failing on the primary cache are replayed when it recovers.
+func (f *FallbackCache) FlushPrefix(ctx context.Context, prefix string) error {
+	flusher, ok := f.primary.(PrefixFlusher)
+	if !ok {
+		return ErrNotSupported
+	}
+	if local, ok := f.fallback.(PrefixFlusher); ok {
+		if err := local.FlushPrefix(ctx, prefix); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	for key := range f.written {
+		if strings.HasPrefix(key, prefix) {
+			delete(f.written, key)
+		}
+	}
+	f.mu.Unlock()
+	if err := flusher.FlushPrefix(ctx, prefix); err != nil {
+		f.failed()
+		f.mu.Lock()
+		f.prefixes = append(f.prefixes, prefix)
+		f.mu.Unlock()
+		return nil
+	}
+	f.succeeded()
+	return nil
+}
+
+// Degraded reports whether the last call of the primary cache failed or pending changes are being replayed to it
+func (f *FallbackCache) Degraded() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.degraded || f.replaying
+}
+
+// pending reports whether key has changes not replayed to the primary cache yet
+func (f *FallbackCache) pending(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.dels[key]; ok {
+		return true
+	}
+	if _, ok := f.written[key]; ok {
+		return true
+	}
+	for _, prefix := range f.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// failed records a failure of the primary cache
+func (f *FallbackCache) failed() {
+	f.mu.Lock()
+	f.degraded = true
+	f.mu.Unlock()
+}
+
+// succeeded records a successful call of the primary cache and starts replaying pending changes if it recovered
+func (f *FallbackCache) succeeded() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.degraded || f.replaying {
+		return
+	}
+	f.degraded = false
+	f.replaying = true
+	go f.replay(context.Background())
+}
+
+// replay deletes, flushes and backfills pending changes to the primary cache, changes failing are kept
+// for the next recovery
+func (f *FallbackCache) replay(ctx context.Context) {
+	err := f.replayDels(ctx)
+	if err == nil {
+		err = f.replayPrefixes(ctx)
+	}
+	if err == nil {
+		err = f.replayWritten(ctx)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replaying = false
+	if err != nil {
+		f.degraded = true
+	}
+}
+
+func (f *FallbackCache) replayDels(ctx context.Context) error {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.dels))
+	for key := range f.dels {
+		keys = append(keys, key)
+	}
+	f.mu.Unlock()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if err := f.primary.(KeyDeleter).Del(ctx, keys...); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	for _, key := range keys {
+		delete(f.dels, key)
+	}
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *FallbackCache) replayPrefixes(ctx context.Context) error {
+	for {
+		f.mu.Lock()
+		if len(f.prefixes) == 0 {
+			f.mu.Unlock()
+			return nil
+		}
+		prefix := f.prefixes[0]
+		f.mu.Unlock()
+
+		if err := f.primary.(PrefixFlusher).FlushPrefix(ctx, prefix); err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.prefixes = f.prefixes[1:]
+		f.mu.Unlock()
+	}
+}
+
+func (f *FallbackCache) replayWritten(ctx context.Context) error {
+	for {
+		var (
+			key      string
+			expireAt time.Time
+			found    bool
+		)
+		f.mu.Lock()
+		for key, expireAt = range f.written {
+			found = true
+			break
+		}
+		f.mu.Unlock()
+		if !found {
+			return nil
+		}
+
+		var ttl time.Duration
+		if !expireAt.IsZero() {
+			ttl = time.Until(expireAt)
+		}
+		value, err := f.fallback.Get(ctx, key)
+		if err == nil && value != nil && (expireAt.IsZero() || ttl > 0) {
+			if err := f.primary.Set(ctx, key, value, ttl); err != nil {
+				return err
+			}
+		}
+
+		f.mu.Lock()
+		// a Set or Del meanwhile removed or replaced the pending entry
+		if current, ok := f.written[key]; ok && current.Equal(expireAt) {
+			delete(f.written, key)
+		}
+		f.mu.Unlock()
+	}
+}
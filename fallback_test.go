@@ -0,0 +1,124 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// downCache is a MemoryCache whose calls fail while down is set
+type downCache struct {
+	*MemoryCache
+	down int32
+}
+
+var errDown = errors.New("connection refused")
+
+func (c *downCache) Get(ctx context.Context, key string) (interface{}, error) {
+	if atomic.LoadInt32(&c.down) == 1 {
+		return nil, errDown
+	}
+	return c.MemoryCache.Get(ctx, key)
+}
+
+func (c *downCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if atomic.LoadInt32(&c.down) == 1 {
+		return errDown
+	}
+	return c.MemoryCache.Set(ctx, key, value, ttl)
+}
+
+func (c *downCache) Del(ctx context.Context, keys ...string) error {
+	if atomic.LoadInt32(&c.down) == 1 {
+		return errDown
+	}
+	return c.MemoryCache.Del(ctx, keys...)
+}
+
+func (c *downCache) FlushPrefix(ctx context.Context, prefix string) error {
+	if atomic.LoadInt32(&c.down) == 1 {
+		return errDown
+	}
+	return c.MemoryCache.FlushPrefix(ctx, prefix)
+}
+
+// TestFallbackCache tests falling back to the local cache and replaying deletions when the primary cache recovers
+func TestFallbackCache(t *testing.T) {
+	ctx := context.Background()
+	primary := &downCache{MemoryCache: NewMemoryCache()}
+	local := NewMemoryCache()
+	defer primary.Close()
+	defer local.Close()
+	cache := NewFallbackCache(primary, local, false)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Hour))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("B"), time.Hour))
+	assert.NoError(t, cache.Set(ctx, "users:1", []byte("U"), time.Hour))
+	_, err := local.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.False(t, cache.Degraded())
+
+	atomic.StoreInt32(&primary.down, 1)
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.True(t, cache.Degraded())
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C"), time.Hour))
+	value, err := cache.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("C"), value)
+	assert.NoError(t, cache.Del(ctx, "a"))
+	assert.NoError(t, cache.FlushPrefix(ctx, "users:"))
+
+	// deleted keys are not read from the primary cache before the deletions are replayed
+	atomic.StoreInt32(&primary.down, 0)
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	value, err = cache.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("B"), value)
+	assert.Eventually(t, func() bool { return !cache.Degraded() }, time.Second, time.Millisecond)
+
+	_, err = primary.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = primary.Get(ctx, "users:1")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = primary.Get(ctx, "c") // dropped without backfill
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+// TestFallbackCacheBackfill tests writing entries set during an outage to the primary cache when it recovers
+func TestFallbackCacheBackfill(t *testing.T) {
+	ctx := context.Background()
+	primary := &downCache{MemoryCache: NewMemoryCache()}
+	local := NewMemoryCache()
+	defer primary.Close()
+	defer local.Close()
+	cache := NewFallbackCache(primary, local, true)
+
+	atomic.StoreInt32(&primary.down, 1)
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Hour))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("B"), 0))
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C"), time.Hour))
+	assert.NoError(t, cache.Del(ctx, "c"))
+
+	atomic.StoreInt32(&primary.down, 0)
+	assert.NoError(t, cache.Set(ctx, "b", []byte("B2"), 0)) // newer than the backfilled value
+	assert.Eventually(t, func() bool { return !cache.Degraded() }, time.Second, time.Millisecond)
+
+	value, err := primary.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	ttl, err := primary.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ttl > 59*time.Minute && ttl <= time.Hour)
+
+	value, err = primary.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("B2"), value)
+	_, err = primary.Get(ctx, "c")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
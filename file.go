@@ -0,0 +1,283 @@
+package grc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileCache is a cache client storing entries as files in a directory, e.g. for tools without redis which restart often.
+// Keys are hashed to file names and spread over 256 subdirectories, entries are written atomically by renaming temporary files.
+type FileCache struct {
+	dir       string
+	maxBytes  int64
+	now       func() time.Time
+	mu        sync.Mutex // serializes writes, deletes and cleanup, reads do not lock as files are replaced atomically
+	bytes     int64      // approximate total size of entry files, recounted by cleanup
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// FileCacheOptions is a struct for file cache options
+type FileCacheOptions struct {
+	MaxBytes        int64            // maximum total size of entry files, the least recently written entries are evicted down to 90% of it, 0 means no limit
+	CleanupInterval time.Duration    // interval of deleting expired entries in the background, 0 means a minute, a negative value disables it
+	Now             func() time.Time // returns the current time for expiration, nil means time.Now
+}
+
+// fileHeaderSize is the size of the expiration time prefixed to values in entry files
+const fileHeaderSize = 8
+
+// fileLowWater is the percentage of MaxBytes entries are evicted down to, so not every write after reaching
+// MaxBytes walks the directory
+const fileLowWater = 90
+
+// fileTempPrefix is the prefix of temporary files, which are renamed to entry files when written completely
+const fileTempPrefix = ".tmp-"
+
+// NewFileCache returns a new FileCache instance storing entries in dir, which is created if not exists,
+// expired entries are cleaned up every CleanupInterval until Close
+func NewFileCache(dir string, options FileCacheOptions) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f := &FileCache{
+		dir:      dir,
+		maxBytes: options.MaxBytes,
+		now:      options.Now,
+		stop:     make(chan struct{}),
+	}
+	if f.now == nil {
+		f.now = time.Now
+	}
+
+	f.mu.Lock()
+	err := f.cleanupLocked()
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	interval := options.CleanupInterval
+	if interval == 0 {
+		interval = time.Minute
+	}
+	if interval > 0 {
+		go f.cleanup(interval)
+	}
+	return f, nil
+}
+
+// path returns the file path of key, named by the sha256 hash of key in a subdirectory of its first byte
+func (f *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(f.dir, name[:2], name)
+}
+
+// Get gets value from file by key, returns ErrCacheMiss if the key does not exist or is expired
+func (f *FileCache) Get(ctx context.Context, key string) (interface{}, error) {
+	data, err := os.ReadFile(f.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrCacheMiss
+		}
+		return nil, err
+	}
+	if len(data) < fileHeaderSize || fileExpired(data, f.now()) {
+		return nil, ErrCacheMiss // expired entries are deleted by cleanup
+	}
+	return data[fileHeaderSize:], nil
+}
+
+// fileExpired reports whether the entry file data is expired
+func fileExpired(data []byte, now time.Time) bool {
+	expireAt := int64(binary.BigEndian.Uint64(data))
+	return expireAt != 0 && now.UnixNano() >= expireAt
+}
+
+// readFileHeader reads the expiration header of an entry file, it is shorter than fileHeaderSize for invalid files
+func readFileHeader(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, fileHeaderSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return header[:n], nil
+}
+
+// Set sets value to file by key with ttl using json encoding, []byte values are set as is, ttl <= 0 means no expiration
+func (f *FileCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = f.now().Add(ttl).UnixNano()
+	}
+	entry := make([]byte, fileHeaderSize+len(data))
+	binary.BigEndian.PutUint64(entry, uint64(expireAt))
+	copy(entry[fileHeaderSize:], data)
+
+	path := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), fileTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(entry)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var old int64
+	if info, err := os.Stat(path); err == nil {
+		old = info.Size()
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	f.bytes += int64(len(entry)) - old
+	if f.maxBytes > 0 && f.bytes > f.maxBytes {
+		return f.cleanupLocked()
+	}
+	return nil
+}
+
+// Del deletes keys from files
+func (f *FileCache) Del(ctx context.Context, keys ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, key := range keys {
+		path := f.path(key)
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		f.bytes -= info.Size()
+	}
+	return nil
+}
+
+// Close stops the background cleanup, if any
+func (f *FileCache) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.stop)
+	})
+	return nil
+}
+
+func (f *FileCache) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			f.mu.Lock()
+			_ = f.cleanupLocked()
+			f.mu.Unlock()
+		}
+	}
+}
+
+// fileEntry is an entry file found by cleanup
+type fileEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// cleanupLocked deletes expired entries and leftover temporary files, recounts the total size
+// and evicts the least recently written entries down to fileLowWater percent of MaxBytes if it is exceeded, f.mu must be held
+func (f *FileCache) cleanupLocked() error {
+	now := f.now()
+	var entries []fileEntry
+	var total int64
+
+	err := filepath.WalkDir(f.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), fileTempPrefix) {
+			// written by a Set in progress or interrupted, Set holds f.mu only to rename
+			if info, err := d.Info(); err == nil && now.Sub(info.ModTime()) > time.Minute {
+				os.Remove(path)
+			}
+			return nil
+		}
+
+		header, err := readFileHeader(path)
+		if err != nil {
+			return nil // deleted concurrently
+		}
+		if len(header) < fileHeaderSize || fileExpired(header, now) {
+			os.Remove(path)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, fileEntry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if f.maxBytes > 0 && total > f.maxBytes {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].modTime.Before(entries[j].modTime)
+		})
+		target := f.maxBytes * fileLowWater / 100
+		for _, entry := range entries {
+			if total <= target {
+				break
+			}
+			if err := os.Remove(entry.path); err == nil {
+				total -= entry.size
+			}
+		}
+	}
+	f.bytes = total
+	return nil
+}
@@ -0,0 +1,115 @@
+package grc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFileCache tests get, set, expiration and deletion of the file cache
+func TestFileCache(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	dir := t.TempDir()
+	cache, err := NewFileCache(dir, FileCacheOptions{CleanupInterval: -1, Now: clock.Now})
+	assert.NoError(t, err)
+	defer cache.Close()
+
+	_, err = cache.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	key := "grc:users:../../etc/passwd" // keys are hashed to safe file names
+	assert.NoError(t, cache.Set(ctx, key, []byte("A"), time.Minute))
+	assert.NoError(t, cache.Set(ctx, "b", TestUser{ID: 1, Name: "B"}, 0))
+	assert.NoError(t, cache.Set(ctx, "c", []byte{}, time.Second))
+
+	value, err := cache.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	assert.True(t, strings.HasPrefix(cache.path(key), dir))
+
+	value, err = cache.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"ID":1,"Name":"B"}`), value)
+
+	value, err = cache.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	clock.Advance(2 * time.Second)
+	_, err = cache.Get(ctx, "c")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.NoError(t, cache.cleanupLocked())
+	_, err = os.Stat(cache.path("c"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, int64(2*fileHeaderSize+1+len(`{"ID":1,"Name":"B"}`)), cache.bytes)
+
+	assert.NoError(t, cache.Del(ctx, key, "b", "missing"))
+	_, err = cache.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, int64(0), cache.bytes)
+
+	// entries survive a restart
+	assert.NoError(t, cache.Set(ctx, "d", []byte("D"), time.Minute))
+	restarted, err := NewFileCache(dir, FileCacheOptions{CleanupInterval: -1, Now: clock.Now})
+	assert.NoError(t, err)
+	defer restarted.Close()
+	value, err = restarted.Get(ctx, "d")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("D"), value)
+	assert.Equal(t, int64(fileHeaderSize+1), restarted.bytes)
+}
+
+// TestFileCacheMaxBytes tests evicting the least recently written entries over MaxBytes down to the low water mark
+func TestFileCacheMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewFileCache(t.TempDir(), FileCacheOptions{MaxBytes: 3 * (fileHeaderSize + 10), CleanupInterval: -1})
+	assert.NoError(t, err)
+	defer cache.Close()
+
+	modTime := time.Now().Add(-time.Hour)
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, cache.Set(ctx, key, []byte("0123456789"), 0))
+		assert.NoError(t, os.Chtimes(cache.path(key), modTime, modTime))
+		modTime = modTime.Add(time.Minute)
+	}
+	assert.NoError(t, cache.Set(ctx, "d", []byte("0123456789"), 0))
+
+	for _, key := range []string{"a", "b"} {
+		_, err = cache.Get(ctx, key)
+		assert.ErrorIs(t, err, ErrCacheMiss, key)
+	}
+	for _, key := range []string{"c", "d"} {
+		_, err = cache.Get(ctx, key)
+		assert.NoError(t, err, key)
+	}
+	assert.Equal(t, int64(2*(fileHeaderSize+10)), cache.bytes)
+
+	// below MaxBytes again, the next write does not evict
+	assert.NoError(t, cache.Set(ctx, "e", []byte("0123456789"), 0))
+	for _, key := range []string{"c", "d", "e"} {
+		_, err = cache.Get(ctx, key)
+		assert.NoError(t, err, key)
+	}
+}
+
+// TestFileCacheTempFiles tests deleting temporary files left by interrupted writes
+func TestFileCacheTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, "ab", fileTempPrefix+"1")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(tmp), 0o755))
+	assert.NoError(t, os.WriteFile(tmp, []byte("partial"), 0o644))
+	old := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(tmp, old, old))
+
+	cache, err := NewFileCache(dir, FileCacheOptions{CleanupInterval: -1})
+	assert.NoError(t, err)
+	defer cache.Close()
+	_, err = os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err))
+}
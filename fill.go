@@ -0,0 +1,134 @@
+package grc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// fillPollInterval is how often a miss waiting for the fill lock holder checks the cache
+const fillPollInterval = 20 * time.Millisecond
+
+// FillLocker is an interface for a distributed lock around cache population,
+// so only one instance queries the database on a miss while the others wait for the entry
+type FillLocker interface {
+	// Lock acquires the lock of key for at most ttl, reports whether it was acquired and returns a function releasing it
+	Lock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// FillLockChecker is an optional interface of fill lockers which can tell whether a lock is held, so a miss waiting
+// for the holder queries the database as soon as the lock is released without an entry, e.g. for an empty result
+type FillLockChecker interface {
+	Locked(ctx context.Context, key string) (bool, error)
+}
+
+// RedisFillLocker is a FillLocker using SET NX PX, a lock is only released by the instance holding it
+type RedisFillLocker struct {
+	Logger Logger // logs failed releases of locks, nil means the standard logger
+
+	client redis.UniversalClient
+}
+
+// NewRedisFillLocker returns a new RedisFillLocker instance
+func NewRedisFillLocker(client redis.UniversalClient) *RedisFillLocker {
+	return &RedisFillLocker{client: client}
+}
+
+// unlockScript deletes KEYS[1] if its value is the token ARGV[1] of the lock holder
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lock sets key to a random token if it does not exist, with ttl
+func (l *RedisFillLocker) Lock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, false, err
+	}
+	token := hex.EncodeToString(b)
+
+	ok, err := l.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	unlock := func() {
+		// the request context may be done, the lock must be released anyway
+		if err := unlockScript.Run(context.Background(), l.client, []string{key}, token).Err(); err != nil {
+			logWith(ctx, l.Logger, LogWarn, "release fill lock failed", err)
+		}
+	}
+	return unlock, true, nil
+}
+
+// Locked reports whether the lock of key is held
+func (l *RedisFillLocker) Locked(ctx context.Context, key string) (bool, error) {
+	n, err := l.client.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+// fillLockKey returns the key of the fill lock of a cache key
+func fillLockKey(key string) string {
+	return key + ":lock"
+}
+
+// fillLockTTL returns the ttl of fill locks, 10s if not configured
+func fillLockTTL(config CacheConfig) time.Duration {
+	if config.FillLockTTL > 0 {
+		return config.FillLockTTL
+	}
+	return 10 * time.Second
+}
+
+// waitFill acquires the fill lock of key on a miss. If another instance holds it, waitFill polls the cache
+// for the entry it populates for at most FillWait and reports a hit, otherwise the caller queries the database.
+// With a FillLockChecker, waiting stops as soon as the lock is released, as the holder may not write an entry,
+// e.g. for empty or oversized results or failed queries.
+// unlock releases the lock if it was acquired and is never nil.
+func (g *GormCache) waitFill(db *gorm.DB, key string, config CacheConfig) (unlock func(), hit bool) {
+	unlock = func() {}
+	ctx := db.Statement.Context
+	release, ok, err := config.FillLocker.Lock(ctx, fillLockKey(key), fillLockTTL(config))
+	if err != nil {
+		logMessage(ctx, config, LogWarn, "acquire fill lock failed", err)
+		return unlock, false
+	}
+	if ok {
+		return release, false
+	}
+
+	wait := config.FillWait
+	if wait <= 0 {
+		wait = time.Second
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(fillPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return unlock, false
+		case <-timer.C:
+			return unlock, false // the holder is slow or gone, query the database
+		case <-ticker.C:
+			released := false
+			if checker, ok := config.FillLocker.(FillLockChecker); ok {
+				locked, err := checker.Locked(ctx, fillLockKey(key))
+				released = err == nil && !locked // checked before the entry, which is written before the release
+			}
+			if hit, _, err := g.loadCache(db, key, config); err == nil && hit {
+				return unlock, true
+			}
+			if released {
+				return unlock, false // the holder is done without an entry, query the database
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestRedisFillLocker tests acquiring and releasing fill locks in redis
+func TestRedisFillLocker(t *testing.T) {
+	ctx := context.Background()
+	locker := NewRedisFillLocker(rdb)
+	defer rdb.Del(ctx, "fill:lock")
+
+	unlock, ok, err := locker.Lock(ctx, "fill:lock", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, ok, err = locker.Lock(ctx, "fill:lock", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	unlock()
+	unlockOther, ok, err := locker.Lock(ctx, "fill:lock", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	unlock() // a released lock does not release the lock of another holder
+	assert.Equal(t, int64(1), rdb.Exists(ctx, "fill:lock").Val())
+	unlockOther()
+	assert.Equal(t, int64(0), rdb.Exists(ctx, "fill:lock").Val())
+}
+
+// TestFillLock tests waiting for the fill lock holder to populate an entry on a miss
+func TestFillLock(t *testing.T) {
+	ctx := context.Background()
+	locker := NewRedisFillLocker(rdb)
+	cache := NewGormCache("fill_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:        time.Minute,
+		Prefix:     "fill:",
+		FillLocker: locker,
+		FillWait:   200 * time.Millisecond,
+	})
+	assert.NoError(t, db.Use(cache))
+
+	query := func() string {
+		var user TestUser
+		assert.NoError(t, Session(db).Where("id = ?", 3).First(&user).Error)
+		return user.Name
+	}
+
+	assert.Equal(t, "43", query()) // acquires the lock and populates the entry
+	keys, err := rdb.Keys(ctx, "fill:test_users:*").Result()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, int64(0), rdb.Exists(ctx, fillLockKey(keys[0])).Val()) // released
+	data, err := rdb.Get(ctx, keys[0]).Bytes()
+	assert.NoError(t, err)
+	defer rdb.Del(ctx, keys[0])
+
+	// the holder does not populate the entry in time, the database is queried
+	assert.NoError(t, rdb.Del(ctx, keys[0]).Err())
+	unlock, ok, err := locker.Lock(ctx, fillLockKey(keys[0]), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	start := time.Now()
+	assert.Equal(t, "43", query())
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	unlock()
+
+	// the holder populates the entry, which is served from the cache
+	assert.NoError(t, rdb.Del(ctx, keys[0]).Err())
+	unlock, ok, err = locker.Lock(ctx, fillLockKey(keys[0]), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	defer unlock()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		rdb.Set(ctx, keys[0], data, time.Minute)
+	}()
+	start = time.Now()
+	assert.Equal(t, "43", query())
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+}
+
+// TestFillLockReleased tests querying the database as soon as the fill lock holder releases the lock without an entry
+func TestFillLockReleased(t *testing.T) {
+	ctx := context.Background()
+	locker := NewRedisFillLocker(rdb)
+	cache := NewGormCache("fill_released_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:        time.Minute,
+		Prefix:     "fill_released:",
+		FillLocker: locker,
+		FillWait:   time.Second,
+	})
+	assert.NoError(t, db.Use(cache))
+
+	// an empty result is not cached without CacheEmptyResults
+	query := func() {
+		var users []TestUser
+		assert.NoError(t, Session(db).Where("id > ?", 1000).Find(&users).Error)
+		assert.Empty(t, users)
+	}
+	query()
+	key, _, _ := cache.Explain(Session(db.Session(&gorm.Session{DryRun: true})).Where("id > ?", 1000).Find(&[]TestUser{}))
+	assert.Equal(t, int64(0), rdb.Exists(ctx, key).Val())
+
+	unlock, ok, err := locker.Lock(ctx, fillLockKey(key), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		unlock() // the holder got an empty result
+	}()
+	start := time.Now()
+	query()
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
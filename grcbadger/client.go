@@ -0,0 +1,125 @@
+// Package grcbadger provides a grc cache client backed by BadgerDB, a persistent cache which can exceed memory
+package grcbadger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/evangwt/grc"
+)
+
+// Options is a struct for badger client options
+type Options struct {
+	GCInterval time.Duration // interval of value log garbage collection, which reclaims space of expired entries, 0 means 5 minutes, a negative value disables it
+}
+
+// Client is a grc.CacheClient backed by BadgerDB using its native ttl, which has a precision of seconds
+type Client struct {
+	db        *badger.DB
+	owned     bool // db is closed by Close
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// Open opens or creates the badger database in dir and returns a new Client instance using it, which is closed by Close
+func Open(dir string, options Options) (*Client, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		return nil, err
+	}
+	c := NewClient(db, options)
+	c.owned = true
+	return c, nil
+}
+
+// NewClient returns a new Client instance using db, db is not closed by Close
+func NewClient(db *badger.DB, options Options) *Client {
+	c := &Client{db: db, stop: make(chan struct{})}
+
+	interval := options.GCInterval
+	if interval == 0 {
+		interval = 5 * time.Minute
+	}
+	if interval > 0 && !db.Opts().InMemory {
+		go c.gc(interval)
+	}
+	return c
+}
+
+// Get gets value from badger by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	var value []byte
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, grc.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Set sets value to badger by key with ttl using json encoding, ttl <= 0 means no expiration
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	entry := badger.NewEntry([]byte(key), data)
+	if ttl > 0 {
+		entry = entry.WithTTL(ttl)
+	}
+	return c.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(entry)
+	})
+}
+
+// Del deletes keys from badger
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	return c.db.Update(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			if err := txn.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close stops the background garbage collection and closes the database if it was opened by Open
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		if c.owned {
+			err = c.db.Close()
+		}
+	})
+	return err
+}
+
+func (c *Client) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			for c.db.RunValueLogGC(0.5) == nil { // rewrite value log files until none is worth it
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package grcbadger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/evangwt/grc"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient tests get, set, expiration and deletion of the badger client
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	assert.NoError(t, err)
+	defer db.Close()
+	client := NewClient(db, Options{})
+	defer client.Close()
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", map[string]int{"id": 1}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte("C"), time.Second))
+
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+
+	assert.Eventually(t, func() bool {
+		_, err := client.Get(ctx, "c")
+		return err == grc.ErrCacheMiss
+	}, 3*time.Second, 100*time.Millisecond)
+
+	assert.NoError(t, client.Del(ctx, "a", "missing"))
+	_, err = client.Get(ctx, "a")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+}
+
+// TestOpen tests that entries of a client opened in a directory survive a restart
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	client, err := Open(dir, Options{})
+	assert.NoError(t, err)
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, client.Close())
+
+	client, err = Open(dir, Options{})
+	assert.NoError(t, err)
+	defer client.Close()
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+}
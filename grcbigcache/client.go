@@ -0,0 +1,89 @@
+// Package grcbigcache provides a grc cache client backed by bigcache, a local cache which keeps entries off the gc heap
+package grcbigcache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/evangwt/grc"
+)
+
+// headerSize is the size of the expiration time prefixed to values, as bigcache has a single LifeWindow for all entries
+const headerSize = 8
+
+// Client is a grc.CacheClient backed by bigcache.
+// Entries expire after the ttl of Set or the LifeWindow of the cache, whichever comes first.
+type Client struct {
+	cache *bigcache.BigCache
+	now   func() time.Time
+}
+
+// NewClient returns a new Client instance using cache, which should not be used for other values
+func NewClient(cache *bigcache.BigCache) *Client {
+	return &Client{cache: cache, now: time.Now}
+}
+
+// New returns a new Client instance with a bigcache of default config, whose entries are evicted after lifeWindow
+func New(ctx context.Context, lifeWindow time.Duration) (*Client, error) {
+	cache, err := bigcache.New(ctx, bigcache.DefaultConfig(lifeWindow))
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(cache), nil
+}
+
+// Get gets value from bigcache by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	data, err := c.cache.Get(key)
+	if err != nil {
+		if errors.Is(err, bigcache.ErrEntryNotFound) {
+			return nil, grc.ErrCacheMiss
+		}
+		return nil, err
+	}
+	if len(data) < headerSize {
+		return nil, grc.ErrCacheMiss // not written by Client
+	}
+
+	expireAt := int64(binary.BigEndian.Uint64(data))
+	if expireAt != 0 && c.now().UnixNano() >= expireAt {
+		_ = c.cache.Delete(key)
+		return nil, grc.ErrCacheMiss
+	}
+	return data[headerSize:], nil
+}
+
+// Set sets value to bigcache by key with ttl using json encoding, ttl <= 0 means the LifeWindow of the cache
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = c.now().Add(ttl).UnixNano()
+	}
+	entry := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint64(entry, uint64(expireAt))
+	copy(entry[headerSize:], data)
+	return c.cache.Set(key, entry)
+}
+
+// Del deletes keys from bigcache
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := c.cache.Delete(key); err != nil && !errors.Is(err, bigcache.ErrEntryNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the bigcache
+func (c *Client) Close() error {
+	return c.cache.Close()
+}
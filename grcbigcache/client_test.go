@@ -0,0 +1,50 @@
+package grcbigcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient tests get, set, expiration and deletion of the bigcache client
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	client, err := New(ctx, time.Hour)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", map[string]int{"id": 1}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte{}, time.Second))
+
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+
+	value, err = client.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	now = now.Add(2 * time.Second)
+	_, err = client.Get(ctx, "c")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+	_, err = client.Get(ctx, "a")
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.Del(ctx, "a", "b", "missing"))
+	_, err = client.Get(ctx, "a")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+}
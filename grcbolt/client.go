@@ -0,0 +1,216 @@
+// Package grcbolt provides a grc cache client backed by bbolt, a persistent cache which survives restarts without external infrastructure
+package grcbolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/evangwt/grc"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Options is a struct for bbolt client options
+type Options struct {
+	Bucket          string           // name of the bucket of entries, the expiration index is stored in Bucket + ".exp", default "grc"
+	CompactInterval time.Duration    // interval of deleting expired entries in the background, 0 means a minute, a negative value disables it
+	Now             func() time.Time // returns the current time for expiration, nil means time.Now
+}
+
+// Client is a grc.CacheClient backed by bbolt.
+// Entries are prefixed with their expiration time and indexed by it, so expired entries are deleted without a full scan.
+type Client struct {
+	db        *bolt.DB
+	owned     bool // db is closed by Close
+	entries   []byte
+	index     []byte
+	now       func() time.Time
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// Open opens or creates the bbolt database at path and returns a new Client instance using it, which is closed by Close
+func Open(path string, options Options) (*Client, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(db, options)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	c.owned = true
+	return c, nil
+}
+
+// NewClient returns a new Client instance using db, creating its buckets, db is not closed by Close
+func NewClient(db *bolt.DB, options Options) (*Client, error) {
+	bucket := options.Bucket
+	if bucket == "" {
+		bucket = "grc"
+	}
+	c := &Client{
+		db:      db,
+		entries: []byte(bucket),
+		index:   []byte(bucket + ".exp"),
+		now:     options.Now,
+		stop:    make(chan struct{}),
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(c.entries); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(c.index)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	interval := options.CompactInterval
+	if interval == 0 {
+		interval = time.Minute
+	}
+	if interval > 0 {
+		go c.compact(interval)
+	}
+	return c, nil
+}
+
+// Get gets value from bbolt by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	var value []byte
+	err := c.db.View(func(tx *bolt.Tx) error {
+		entry := tx.Bucket(c.entries).Get([]byte(key))
+		if entry == nil {
+			return grc.ErrCacheMiss
+		}
+		expireAt := int64(binary.BigEndian.Uint64(entry))
+		if expireAt != 0 && c.now().UnixNano() >= expireAt {
+			return grc.ErrCacheMiss
+		}
+		value = append([]byte{}, entry[8:]...) // entry is only valid in the transaction
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Set sets value to bbolt by key with ttl using json encoding, ttl <= 0 means no expiration
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = c.now().Add(ttl).UnixNano()
+	}
+	entry := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(entry, uint64(expireAt))
+	copy(entry[8:], data)
+
+	return c.db.Update(func(tx *bolt.Tx) error {
+		if err := c.delete(tx, []byte(key)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(c.entries).Put([]byte(key), entry); err != nil {
+			return err
+		}
+		if expireAt == 0 {
+			return nil
+		}
+		return tx.Bucket(c.index).Put(indexKey(expireAt, []byte(key)), nil)
+	})
+}
+
+// Del deletes keys from bbolt
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		for _, key := range keys {
+			if err := c.delete(tx, []byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close stops the background compaction and closes the database if it was opened by Open
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		if c.owned {
+			err = c.db.Close()
+		}
+	})
+	return err
+}
+
+// indexKey returns the key of an entry in the expiration index, which is ordered by expiration time
+func indexKey(expireAt int64, key []byte) []byte {
+	buf := make([]byte, 8+len(key))
+	binary.BigEndian.PutUint64(buf, uint64(expireAt))
+	copy(buf[8:], key)
+	return buf
+}
+
+// delete deletes an entry and its expiration index key
+func (c *Client) delete(tx *bolt.Tx, key []byte) error {
+	entries := tx.Bucket(c.entries)
+	entry := entries.Get(key)
+	if entry == nil {
+		return nil
+	}
+	if expireAt := int64(binary.BigEndian.Uint64(entry)); expireAt != 0 {
+		if err := tx.Bucket(c.index).Delete(indexKey(expireAt, key)); err != nil {
+			return err
+		}
+	}
+	return entries.Delete(key)
+}
+
+func (c *Client) compact(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			_ = c.deleteExpired()
+		}
+	}
+}
+
+// deleteExpired deletes expired entries in the order of the expiration index, proportional to their number
+func (c *Client) deleteExpired() error {
+	now := make([]byte, 8)
+	binary.BigEndian.PutUint64(now, uint64(c.now().UnixNano()))
+
+	return c.db.Update(func(tx *bolt.Tx) error {
+		entries := tx.Bucket(c.entries)
+		cursor := tx.Bucket(c.index).Cursor()
+		for k, _ := cursor.First(); k != nil && bytes.Compare(k[:8], now) <= 0; k, _ = cursor.First() {
+			if err := entries.Delete(k[8:]); err != nil {
+				return err
+			}
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package grcbolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+// TestClient tests get, set, expiration, compaction and persistence of the bbolt client
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	now := time.Now()
+	options := Options{CompactInterval: -1, Now: func() time.Time { return now }}
+
+	client, err := Open(path, options)
+	assert.NoError(t, err)
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", map[string]int{"id": 1}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte("C"), time.Second))
+	assert.NoError(t, client.Set(ctx, "c", []byte("C2"), time.Second)) // replaces the index key
+
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+
+	now = now.Add(2 * time.Second)
+	_, err = client.Get(ctx, "c")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.deleteExpired())
+	assert.Equal(t, 2, count(t, client.db, client.entries))
+	assert.Equal(t, 1, count(t, client.db, client.index))
+
+	assert.NoError(t, client.Del(ctx, "a", "missing"))
+	assert.Equal(t, 1, count(t, client.db, client.entries))
+	assert.Equal(t, 0, count(t, client.db, client.index))
+	assert.NoError(t, client.Close())
+
+	// entries survive a restart
+	client, err = Open(path, options)
+	assert.NoError(t, err)
+	defer client.Close()
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+}
+
+func count(t *testing.T, db *bolt.DB, bucket []byte) int {
+	n := 0
+	assert.NoError(t, db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(bucket).Stats().KeyN
+		return nil
+	}))
+	return n
+}
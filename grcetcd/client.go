@@ -0,0 +1,76 @@
+// Package grcetcd provides a grc cache client backed by etcd, using leases for ttl
+package grcetcd
+
+import (
+	"context"
+	"time"
+
+	"github.com/evangwt/grc"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Client is a grc.CacheClient backed by etcd, an entry with ttl is attached to a new lease of the ttl rounded up to seconds,
+// etcd may extend short leases to its minimum lease ttl
+type Client struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// NewClient returns a new Client instance storing entries in client under prefix, e.g. "/grc/"
+func NewClient(client *clientv3.Client, prefix string) *Client {
+	return &Client{client: client, prefix: prefix}
+}
+
+// Get gets value from etcd by key, returns grc.ErrCacheMiss if the key does not exist or its lease expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	resp, err := c.client.Get(ctx, c.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, grc.ErrCacheMiss
+	}
+	value := resp.Kvs[0].Value
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// Set sets value to etcd by key with ttl using json encoding, ttl <= 0 means no expiration
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	var opts []clientv3.OpOption
+	if ttl > 0 {
+		lease, err := c.client.Grant(ctx, int64((ttl+time.Second-1)/time.Second))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, clientv3.WithLease(lease.ID))
+	}
+	_, err = c.client.Put(ctx, c.prefix+key, string(data), opts...)
+	return err
+}
+
+// Del deletes keys from etcd in one transaction
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	ops := make([]clientv3.Op, len(keys))
+	for i, key := range keys {
+		ops[i] = clientv3.OpDelete(c.prefix + key)
+	}
+	_, err := c.client.Txn(ctx).Then(ops...).Commit()
+	return err
+}
+
+// FlushPrefix deletes all keys under prefix from etcd
+func (c *Client) FlushPrefix(ctx context.Context, prefix string) error {
+	_, err := c.client.Delete(ctx, c.prefix+prefix, clientv3.WithPrefix())
+	return err
+}
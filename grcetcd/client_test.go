@@ -0,0 +1,76 @@
+package grcetcd
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// TestClient tests get, set, lease expiration and deletion of the etcd client
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	cfg.LCUrls, cfg.ACUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	cfg.LPUrls, cfg.APUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	etcd, err := embed.StartEtcd(cfg)
+	assert.NoError(t, err)
+	defer etcd.Close()
+	<-etcd.Server.ReadyNotify()
+
+	etcdClient, err := clientv3.New(clientv3.Config{Endpoints: []string{clientURL.String()}, DialTimeout: 5 * time.Second})
+	assert.NoError(t, err)
+	defer etcdClient.Close()
+	client := NewClient(etcdClient, "/grc/")
+	var _ grc.PrefixFlusher = client
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "users:1", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "users:2", map[string]int{"id": 2}, 0))
+	assert.NoError(t, client.Set(ctx, "orders:1", []byte{}, time.Second))
+
+	value, err := client.Get(ctx, "users:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = client.Get(ctx, "users:2")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":2}`), value)
+
+	value, err = client.Get(ctx, "orders:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	assert.Eventually(t, func() bool {
+		_, err := client.Get(ctx, "orders:1")
+		return err == grc.ErrCacheMiss
+	}, 10*time.Second, 100*time.Millisecond)
+
+	assert.NoError(t, client.Del(ctx, "users:1", "missing"))
+	_, err = client.Get(ctx, "users:1")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.FlushPrefix(ctx, "users:"))
+	_, err = client.Get(ctx, "users:2")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+}
+
+// freeURL returns an url of a free local port
+func freeURL(t *testing.T) url.URL {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return url.URL{Scheme: "http", Host: listener.Addr().String()}
+}
@@ -0,0 +1,95 @@
+// Package grcfreecache provides a grc cache client backed by freecache, a local cache with zero gc overhead
+package grcfreecache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coocood/freecache"
+	"github.com/evangwt/grc"
+)
+
+// Client is a grc.CacheClientExt backed by freecache, ttls are rounded up to whole seconds
+type Client struct {
+	cache *freecache.Cache
+}
+
+// NewClient returns a new Client instance using cache, which should not be used for other values
+func NewClient(cache *freecache.Cache) *Client {
+	return &Client{cache: cache}
+}
+
+// New returns a new Client instance with a freecache of size bytes, at least 512KB
+func New(size int) *Client {
+	return NewClient(freecache.NewCache(size))
+}
+
+// expireSeconds converts ttl to freecache seconds, rounding up so short ttls do not mean no expiration
+func expireSeconds(ttl time.Duration) int {
+	if ttl <= 0 {
+		return 0 // no expiration
+	}
+	return int((ttl + time.Second - 1) / time.Second)
+}
+
+// cacheError maps freecache.ErrNotFound to grc.ErrCacheMiss
+func cacheError(err error) error {
+	if errors.Is(err, freecache.ErrNotFound) {
+		return grc.ErrCacheMiss
+	}
+	return err
+}
+
+// Get gets value from freecache by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	value, err := c.cache.Get([]byte(key))
+	if err != nil {
+		return nil, cacheError(err)
+	}
+	return value, nil
+}
+
+// GetWithTTL gets value from freecache by key and its remaining ttl
+func (c *Client) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	value, err := c.Get(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl, err := c.TTL(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, ttl, nil
+}
+
+// Set sets value to freecache by key with ttl using json encoding, ttl <= 0 means no expiration
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+	return c.cache.Set([]byte(key), data, expireSeconds(ttl))
+}
+
+// Touch sets the ttl of a key in freecache, ttl <= 0 removes the expiration
+func (c *Client) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return cacheError(c.cache.Touch([]byte(key), expireSeconds(ttl)))
+}
+
+// TTL gets the remaining ttl of a key in freecache
+func (c *Client) TTL(ctx context.Context, key string) (time.Duration, error) {
+	seconds, err := c.cache.TTL([]byte(key))
+	if err != nil {
+		return 0, cacheError(err)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// Del deletes keys from freecache
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		c.cache.Del([]byte(key))
+	}
+	return nil
+}
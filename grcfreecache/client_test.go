@@ -0,0 +1,67 @@
+package grcfreecache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coocood/freecache"
+	"github.com/evangwt/grc"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTimer is a freecache.Timer which only moves forward when its seconds are added to
+type fakeTimer struct {
+	now uint32
+}
+
+func (t *fakeTimer) Now() uint32 {
+	return atomic.LoadUint32(&t.now)
+}
+
+// TestClient tests get, set, ttl conversion, expiration and deletion of the freecache client
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	timer := &fakeTimer{now: 1000}
+	client := NewClient(freecache.NewCacheCustomTimer(1<<20, timer))
+	var _ grc.CacheClientExt = client
+
+	_, err := client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+	_, err = client.TTL(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+	assert.ErrorIs(t, client.Touch(ctx, "missing", time.Minute), grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", map[string]int{"id": 1}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte("C"), 100*time.Millisecond)) // rounded up to a second
+
+	value, ttl, err := client.GetWithTTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	assert.Equal(t, time.Minute, ttl)
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+	ttl, err = client.TTL(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	ttl, err = client.TTL(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, ttl)
+
+	assert.NoError(t, client.Touch(ctx, "a", time.Hour))
+	atomic.AddUint32(&timer.now, 2)
+	_, err = client.Get(ctx, "c")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+	ttl, err = client.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour-2*time.Second, ttl)
+
+	assert.NoError(t, client.Del(ctx, "a", "b", "missing"))
+	_, err = client.Get(ctx, "a")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+}
@@ -0,0 +1,57 @@
+// Package grcgrpc provides a grc cache client and server of a gRPC cache service, for a centralized caching tier
+// shared by many services. The protocol is defined in grc.proto, regenerate the code with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative grc.proto
+package grcgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/evangwt/grc"
+	"google.golang.org/grpc"
+)
+
+// Client is a grc.CacheClient of a gRPC cache service
+type Client struct {
+	client CacheServiceClient
+}
+
+// NewClient returns a new Client instance of the cache service on conn
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{client: NewCacheServiceClient(conn)}
+}
+
+// Get gets value from the service by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	resp, err := c.client.Get(ctx, &GetRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Found {
+		return nil, grc.ErrCacheMiss
+	}
+	if resp.Value == nil {
+		return []byte{}, nil
+	}
+	return resp.Value, nil
+}
+
+// Set sets value to the service by key with ttl using json encoding, ttl <= 0 means no expiration
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	_, err = c.client.Set(ctx, &SetRequest{Key: key, Value: data, TtlMs: ttl.Milliseconds()})
+	return err
+}
+
+// Del deletes keys from the service
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	_, err := c.client.Del(ctx, &DelRequest{Keys: keys})
+	return err
+}
@@ -0,0 +1,60 @@
+package grcgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// TestClient tests get, set and deletion of the gRPC client against the server
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	backend := grc.NewMemoryCache()
+	defer backend.Close()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	RegisterCacheServiceServer(server, NewServer(backend))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := NewClient(conn)
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", map[string]int{"id": 1}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte{}, time.Second))
+
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	ttl, err := backend.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+
+	value, err = client.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	assert.NoError(t, client.Del(ctx, "a", "missing"))
+	_, err = client.Get(ctx, "a")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+}
@@ -0,0 +1,60 @@
+package grcgrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server is a CacheServiceServer storing entries in a grc.CacheClient, e.g. a RedisClient,
+// register it with RegisterCacheServiceServer
+type Server struct {
+	UnimplementedCacheServiceServer
+	client grc.CacheClient
+}
+
+// NewServer returns a new Server instance storing entries in client
+func NewServer(client grc.CacheClient) *Server {
+	return &Server{client: client}
+}
+
+// Get implements CacheServiceServer
+func (s *Server) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	value, err := s.client.Get(ctx, req.Key)
+	if errors.Is(err, grc.ErrCacheMiss) || errors.Is(err, redis.Nil) {
+		return &GetResponse{}, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &GetResponse{Found: true, Value: data}, nil
+}
+
+// Set implements CacheServiceServer
+func (s *Server) Set(ctx context.Context, req *SetRequest) (*SetResponse, error) {
+	if err := s.client.Set(ctx, req.Key, req.Value, time.Duration(req.TtlMs)*time.Millisecond); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &SetResponse{}, nil
+}
+
+// Del implements CacheServiceServer, the cache client must implement grc.KeyDeleter
+func (s *Server) Del(ctx context.Context, req *DelRequest) (*DelResponse, error) {
+	deleter, ok := s.client.(grc.KeyDeleter)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, grc.ErrNotSupported.Error())
+	}
+	if err := deleter.Del(ctx, req.Keys...); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &DelResponse{}, nil
+}
@@ -0,0 +1,174 @@
+// Package grcmemberlist provides a distributed in-process grc cache client, replicas of a service form a memberlist cluster
+// and gossip invalidations, and optionally small entries, to each other without redis
+package grcmemberlist
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/hashicorp/memberlist"
+)
+
+// Options is a struct for distributed cache options
+type Options struct {
+	Config         *memberlist.Config // memberlist config, nil means memberlist.DefaultLANConfig(), its Delegate is set by New
+	Join           []string           // addresses of existing members to join, empty starts a new cluster
+	ReplicateBytes int                // replicate sets of values up to this size to all replicas, 0 disables replication, gossip messages must fit in a udp packet
+}
+
+// Cache is a grc.CacheClient storing entries in a local MemoryCache on each replica,
+// deletes are gossiped to all replicas and sets of small values are replicated if enabled.
+// Replicas converge eventually, a replica may serve a deleted entry until the invalidation reaches it.
+type Cache struct {
+	local          *grc.MemoryCache
+	list           *memberlist.Memberlist
+	broadcasts     *memberlist.TransmitLimitedQueue
+	replicateBytes int
+}
+
+// message is a gossiped operation
+type message struct {
+	Op    string `json:"op"` // "set" or "del"
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+	TTL   int64  `json:"ttl,omitempty"` // in milliseconds
+}
+
+// New returns a new Cache instance storing entries in local and joins the cluster
+func New(local *grc.MemoryCache, options Options) (*Cache, error) {
+	config := options.Config
+	if config == nil {
+		config = memberlist.DefaultLANConfig()
+	}
+	c := &Cache{local: local, replicateBytes: options.ReplicateBytes}
+	config.Delegate = &delegate{cache: c}
+
+	list, err := memberlist.Create(config)
+	if err != nil {
+		return nil, err
+	}
+	c.list = list
+	c.broadcasts = &memberlist.TransmitLimitedQueue{
+		NumNodes:       list.NumMembers,
+		RetransmitMult: config.RetransmitMult,
+	}
+
+	if len(options.Join) > 0 {
+		if _, err := list.Join(options.Join); err != nil {
+			list.Shutdown()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Members returns the number of alive members of the cluster, including this replica
+func (c *Cache) Members() int {
+	return c.list.NumMembers()
+}
+
+// Get gets value from the local memory cache by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Cache) Get(ctx context.Context, key string) (interface{}, error) {
+	return c.local.Get(ctx, key)
+}
+
+// Set sets value to the local memory cache by key with ttl and replicates it if it is small enough
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+	if err := c.local.Set(ctx, key, data, ttl); err != nil {
+		return err
+	}
+	if len(data) <= c.replicateBytes {
+		c.broadcast(message{Op: "set", Key: key, Value: data, TTL: ttl.Milliseconds()})
+	}
+	return nil
+}
+
+// Del deletes keys from the local memory cache and gossips the deletes to all replicas
+func (c *Cache) Del(ctx context.Context, keys ...string) error {
+	if err := c.local.Del(ctx, keys...); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		c.broadcast(message{Op: "del", Key: key})
+	}
+	return nil
+}
+
+// Close leaves the cluster, waiting at most timeout for the leave to be gossiped, and stops memberlist
+func (c *Cache) Close(timeout time.Duration) error {
+	if err := c.list.Leave(timeout); err != nil {
+		log.Printf("leave cluster failed: %v", err)
+	}
+	return c.list.Shutdown()
+}
+
+func (c *Cache) broadcast(msg message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	c.broadcasts.QueueBroadcast(&broadcast{key: msg.Key, msg: data})
+}
+
+// apply applies a gossiped operation to the local memory cache
+func (c *Cache) apply(data []byte) {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("invalid cache message: %v", err)
+		return
+	}
+	ctx := context.Background()
+	switch msg.Op {
+	case "set":
+		_ = c.local.Set(ctx, msg.Key, msg.Value, time.Duration(msg.TTL)*time.Millisecond)
+	case "del":
+		_ = c.local.Del(ctx, msg.Key)
+	}
+}
+
+// broadcast is a queued message, a newer message of the same key replaces it
+type broadcast struct {
+	key string
+	msg []byte
+}
+
+func (b *broadcast) Invalidates(other memberlist.Broadcast) bool {
+	o, ok := other.(*broadcast)
+	return ok && o.key == b.key
+}
+
+func (b *broadcast) Message() []byte {
+	return b.msg
+}
+
+func (b *broadcast) Finished() {}
+
+// delegate receives gossiped messages of memberlist
+type delegate struct {
+	cache *Cache
+}
+
+func (d *delegate) NodeMeta(limit int) []byte {
+	return nil
+}
+
+func (d *delegate) NotifyMsg(msg []byte) {
+	d.cache.apply(msg)
+}
+
+func (d *delegate) GetBroadcasts(overhead, limit int) [][]byte {
+	return d.cache.broadcasts.GetBroadcasts(overhead, limit)
+}
+
+func (d *delegate) LocalState(join bool) []byte {
+	return nil
+}
+
+func (d *delegate) MergeRemoteState(buf []byte, join bool) {}
@@ -0,0 +1,55 @@
+package grcmemberlist
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/hashicorp/memberlist"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCache(t *testing.T, name string, join []string) *Cache {
+	config := memberlist.DefaultLocalConfig()
+	config.Name = name
+	config.BindAddr = "127.0.0.1"
+	config.BindPort = 0
+	config.GossipInterval = 10 * time.Millisecond
+	config.Logger = log.New(io.Discard, "", 0)
+
+	local := grc.NewMemoryCache()
+	t.Cleanup(func() { local.Close() })
+	cache, err := New(local, Options{Config: config, Join: join, ReplicateBytes: 1024})
+	assert.NoError(t, err)
+	t.Cleanup(func() { cache.Close(time.Second) })
+	return cache
+}
+
+// TestCache tests replicating sets and gossiping deletes between replicas
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	a := newTestCache(t, "a", nil)
+	node := a.list.LocalNode()
+	b := newTestCache(t, "b", []string{fmt.Sprintf("%s:%d", node.Addr, node.Port)})
+	assert.Equal(t, 2, a.Members())
+	assert.Equal(t, 2, b.Members())
+
+	assert.NoError(t, a.Set(ctx, "small", []byte("A"), time.Minute))
+	assert.NoError(t, a.Set(ctx, "large", make([]byte, 2048), time.Minute)) // not replicated
+	assert.Eventually(t, func() bool {
+		value, err := b.Get(ctx, "small")
+		return err == nil && string(value.([]byte)) == "A"
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err := b.Get(ctx, "large")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, b.Del(ctx, "small"))
+	assert.Eventually(t, func() bool {
+		_, err := a.Get(ctx, "small")
+		return err == grc.ErrCacheMiss
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,95 @@
+// Package grcnats provides a grc cache client backed by a NATS JetStream key-value bucket
+package grcnats
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/nats-io/nats.go"
+)
+
+// headerSize is the size of the expiration time prefixed to values, as buckets have a single ttl for all keys
+const headerSize = 8
+
+// Client is a grc.CacheClient backed by a JetStream key-value bucket.
+// Entries expire after the ttl of Set, expired entries are deleted from the bucket after its ttl,
+// so the ttl of the bucket should be at least the longest ttl of entries.
+type Client struct {
+	kv  nats.KeyValue
+	now func() time.Time
+}
+
+// NewClient returns a new Client instance using the key-value bucket kv, which should not be used for other values
+func NewClient(kv nats.KeyValue) *Client {
+	return &Client{kv: kv, now: time.Now}
+}
+
+// New returns a new Client instance using the key-value bucket named bucket, which is created with ttl if not exists
+func New(js nats.JetStreamContext, bucket string, ttl time.Duration) (*Client, error) {
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket, TTL: ttl, History: 1})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(kv), nil
+}
+
+// bucketKey encodes key to the characters allowed in bucket keys
+func bucketKey(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// Get gets value from the bucket by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	entry, err := c.kv.Get(bucketKey(key))
+	if err != nil {
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return nil, grc.ErrCacheMiss
+		}
+		return nil, err
+	}
+
+	data := entry.Value()
+	if len(data) < headerSize {
+		return nil, grc.ErrCacheMiss // not written by Client
+	}
+	expireAt := int64(binary.BigEndian.Uint64(data))
+	if expireAt != 0 && c.now().UnixNano() >= expireAt {
+		return nil, grc.ErrCacheMiss
+	}
+	return data[headerSize:], nil
+}
+
+// Set sets value to the bucket by key with ttl using json encoding, ttl <= 0 means the ttl of the bucket
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = c.now().Add(ttl).UnixNano()
+	}
+	entry := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint64(entry, uint64(expireAt))
+	copy(entry[headerSize:], data)
+	_, err = c.kv.Put(bucketKey(key), entry)
+	return err
+}
+
+// Del deletes keys from the bucket, purging their history
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := c.kv.Purge(bucketKey(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package grcnats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient tests get, set, expiration and deletion of the JetStream key-value client
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	assert.NoError(t, err)
+	go s.Start()
+	defer s.Shutdown()
+	assert.True(t, s.ReadyForConnections(5*time.Second))
+
+	nc, err := nats.Connect(s.ClientURL())
+	assert.NoError(t, err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	assert.NoError(t, err)
+
+	client, err := New(js, "grc", time.Hour)
+	assert.NoError(t, err)
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	key := "grc:users:1" // encoded, as ':' is not allowed in bucket keys
+	assert.NoError(t, client.Set(ctx, key, []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", map[string]int{"id": 1}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte{}, time.Second))
+
+	value, err := client.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+
+	value, err = client.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	now = now.Add(2 * time.Second)
+	_, err = client.Get(ctx, "c")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Del(ctx, key, "missing"))
+	_, err = client.Get(ctx, key)
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	// an existing bucket is reused
+	client, err = New(js, "grc", time.Hour)
+	assert.NoError(t, err)
+	_, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+}
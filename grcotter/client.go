@@ -0,0 +1,78 @@
+// Package grcotter provides a grc cache client backed by otter, a local cache with W-TinyLFU eviction
+package grcotter
+
+import (
+	"context"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/maypok86/otter"
+)
+
+// noExpiration is the ttl of entries set without expiration, as otter has no such ttl in caches with variable ttl
+const noExpiration = 10 * 365 * 24 * time.Hour
+
+// Client is a grc.CacheClient backed by an otter cache with variable ttl, ttls are rounded up to whole seconds
+type Client struct {
+	cache otter.CacheWithVariableTTL[string, []byte]
+}
+
+// NewClient returns a new Client instance using cache, which should not be used for other values
+func NewClient(cache otter.CacheWithVariableTTL[string, []byte]) *Client {
+	return &Client{cache: cache}
+}
+
+// New returns a new Client instance with an otter cache of at most capacity bytes of keys and values
+func New(capacity int) (*Client, error) {
+	builder, err := otter.NewBuilder[string, []byte](capacity)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := builder.
+		Cost(func(key string, value []byte) uint32 {
+			return uint32(len(key) + len(value))
+		}).
+		WithVariableTTL().
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(cache), nil
+}
+
+// Get gets value from otter by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	value, ok := c.cache.Get(key)
+	if !ok {
+		return nil, grc.ErrCacheMiss
+	}
+	return value, nil
+}
+
+// Set sets value to otter by key with ttl using json encoding, ttl <= 0 means no expiration,
+// values larger than the capacity are not cached
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = noExpiration
+	}
+	c.cache.Set(key, data, ttl)
+	return nil
+}
+
+// Del deletes keys from otter
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		c.cache.Delete(key)
+	}
+	return nil
+}
+
+// Close stops the otter cache
+func (c *Client) Close() error {
+	c.cache.Close()
+	return nil
+}
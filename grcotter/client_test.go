@@ -0,0 +1,49 @@
+package grcotter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient tests get, set, expiration and deletion of the otter client
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	client, err := New(1 << 20)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", map[string]int{"id": 1}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte("C"), time.Second))
+
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+
+	assert.Eventually(t, func() bool {
+		_, err := client.Get(ctx, "c")
+		return err == grc.ErrCacheMiss
+	}, 5*time.Second, 100*time.Millisecond)
+	_, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.Del(ctx, "a", "b"))
+	_, err = client.Get(ctx, "a")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	// values larger than the capacity are dropped
+	assert.NoError(t, client.Set(ctx, "large", make([]byte, 2<<20), time.Minute))
+	_, err = client.Get(ctx, "large")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+}
@@ -0,0 +1,115 @@
+// Package grcprom exposes grc cache statistics as prometheus metrics
+package grcprom
+
+import (
+	"sync"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector exposing the statistics of GormCache instances, e.g. hits, misses, sets,
+// errors and bytes written by table, and get/set latency of cache clients, labeled by cache name
+type Collector struct {
+	mu            sync.Mutex
+	caches        []*grc.GormCache
+	hits          *prometheus.Desc
+	misses        *prometheus.Desc
+	sets          *prometheus.Desc
+	errors        *prometheus.Desc
+	bytes         *prometheus.Desc
+	stale         *prometheus.Desc
+	oversized     *prometheus.Desc
+	tableSets     *prometheus.Desc
+	tableBytes    *prometheus.Desc
+	stageDuration *prometheus.Desc
+	stageErrors   *prometheus.Desc
+	latency       *prometheus.HistogramVec
+}
+
+// NewCollector returns a new Collector instance
+func NewCollector() *Collector {
+	return &Collector{
+		hits:   prometheus.NewDesc("grc_cache_hits_total", "Number of queries served from cache.", []string{"cache"}, nil),
+		misses: prometheus.NewDesc("grc_cache_misses_total", "Number of queries not found in cache.", []string{"cache"}, nil),
+		sets:   prometheus.NewDesc("grc_cache_sets_total", "Number of entries written to cache.", []string{"cache"}, nil),
+		errors: prometheus.NewDesc("grc_cache_errors_total", "Number of failed cache reads and writes.", []string{"cache"}, nil),
+		bytes:  prometheus.NewDesc("grc_cache_bytes_written_total", "Bytes of entries written to cache.", []string{"cache"}, nil),
+		stale: prometheus.NewDesc("grc_cache_stale_hits_total", "Number of hits served stale while being revalidated, also counted in hits.",
+			[]string{"cache"}, nil),
+		oversized: prometheus.NewDesc("grc_cache_oversized_total", "Number of results not cached as they exceed the maximum value size.",
+			[]string{"cache"}, nil),
+		tableSets: prometheus.NewDesc("grc_cache_table_sets_total", "Number of entries written to cache by table.",
+			[]string{"cache", "table"}, nil),
+		tableBytes: prometheus.NewDesc("grc_cache_table_bytes_written_total", "Bytes of entries written to cache by table.",
+			[]string{"cache", "table"}, nil),
+		stageDuration: prometheus.NewDesc("grc_cache_stage_duration_seconds_total", "Time spent in codec stages, serialization is counted under the codec name.",
+			[]string{"cache", "stage"}, nil),
+		stageErrors: prometheus.NewDesc("grc_cache_stage_errors_total", "Number of failed encode and decode calls of codec stages.",
+			[]string{"cache", "stage"}, nil),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grc_cache_operation_duration_seconds",
+			Help:    "Latency of cache client operations.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8), // 100us to ~1.6s
+		}, []string{"cache", "op"}),
+	}
+}
+
+// Add adds caches whose statistics are exposed by the collector
+func (c *Collector) Add(caches ...*grc.GormCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.caches = append(c.caches, caches...)
+}
+
+// Middleware returns a grc.Middleware observing the latency of cache client operations under the cache name
+func (c *Collector) Middleware(name string) grc.Middleware {
+	return grc.WithMetrics(func(op string, duration time.Duration, err error) {
+		c.latency.WithLabelValues(name, op).Observe(duration.Seconds())
+	})
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.sets
+	ch <- c.errors
+	ch <- c.bytes
+	ch <- c.stale
+	ch <- c.oversized
+	ch <- c.tableSets
+	ch <- c.tableBytes
+	ch <- c.stageDuration
+	ch <- c.stageErrors
+	c.latency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	caches := c.caches
+	c.mu.Unlock()
+
+	for _, cache := range caches {
+		stats := cache.Stats()
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), cache.Name())
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), cache.Name())
+		ch <- prometheus.MustNewConstMetric(c.sets, prometheus.CounterValue, float64(stats.Sets), cache.Name())
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(stats.Errors), cache.Name())
+		ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.CounterValue, float64(stats.Bytes), cache.Name())
+		ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(stats.Stale), cache.Name())
+		ch <- prometheus.MustNewConstMetric(c.oversized, prometheus.CounterValue, float64(stats.Oversized), cache.Name())
+		for table, tableStats := range stats.Tables {
+			ch <- prometheus.MustNewConstMetric(c.tableSets, prometheus.CounterValue, float64(tableStats.Sets), cache.Name(), table)
+			ch <- prometheus.MustNewConstMetric(c.tableBytes, prometheus.CounterValue, float64(tableStats.Bytes), cache.Name(), table)
+		}
+		for stage, stageStats := range stats.Stages {
+			ch <- prometheus.MustNewConstMetric(c.stageDuration, prometheus.CounterValue, stageStats.Duration.Seconds(), cache.Name(), stage)
+			ch <- prometheus.MustNewConstMetric(c.stageErrors, prometheus.CounterValue, float64(stageStats.Errors), cache.Name(), stage)
+		}
+	}
+	c.latency.Collect(ch)
+}
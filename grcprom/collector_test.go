@@ -0,0 +1,107 @@
+package grcprom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/glebarez/sqlite"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testUser struct {
+	ID   int
+	Name string
+}
+
+// TestCollector tests exposing cache statistics and client latency of cached queries
+func TestCollector(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&testUser{}))
+	assert.NoError(t, db.Create(&[]testUser{{Name: "A"}, {Name: "B"}}).Error)
+
+	collector := NewCollector()
+	memory := grc.NewMemoryCache()
+	defer memory.Close()
+	cache := grc.NewGormCache("my_cache", grc.Chain(memory, collector.Middleware("my_cache")), grc.CacheConfig{TTL: time.Minute})
+	assert.NoError(t, db.Use(cache))
+	collector.Add(cache)
+
+	registry := prometheus.NewPedanticRegistry()
+	assert.NoError(t, registry.Register(collector))
+
+	// a miss and set, then a hit
+	for i := 0; i < 2; i++ {
+		var users []testUser
+		assert.NoError(t, grc.Session(db).Find(&users).Error)
+		assert.Len(t, users, 2)
+	}
+	data, err := json.Marshal([]testUser{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}})
+	assert.NoError(t, err)
+
+	expected := fmt.Sprintf(`
+# HELP grc_cache_hits_total Number of queries served from cache.
+# TYPE grc_cache_hits_total counter
+grc_cache_hits_total{cache="my_cache"} 1
+# HELP grc_cache_misses_total Number of queries not found in cache.
+# TYPE grc_cache_misses_total counter
+grc_cache_misses_total{cache="my_cache"} 1
+# HELP grc_cache_sets_total Number of entries written to cache.
+# TYPE grc_cache_sets_total counter
+grc_cache_sets_total{cache="my_cache"} 1
+# HELP grc_cache_errors_total Number of failed cache reads and writes.
+# TYPE grc_cache_errors_total counter
+grc_cache_errors_total{cache="my_cache"} 0
+# HELP grc_cache_bytes_written_total Bytes of entries written to cache.
+# TYPE grc_cache_bytes_written_total counter
+grc_cache_bytes_written_total{cache="my_cache"} %[1]d
+# HELP grc_cache_stale_hits_total Number of hits served stale while being revalidated, also counted in hits.
+# TYPE grc_cache_stale_hits_total counter
+grc_cache_stale_hits_total{cache="my_cache"} 0
+# HELP grc_cache_oversized_total Number of results not cached as they exceed the maximum value size.
+# TYPE grc_cache_oversized_total counter
+grc_cache_oversized_total{cache="my_cache"} 0
+# HELP grc_cache_table_sets_total Number of entries written to cache by table.
+# TYPE grc_cache_table_sets_total counter
+grc_cache_table_sets_total{cache="my_cache",table="test_users"} 1
+# HELP grc_cache_table_bytes_written_total Bytes of entries written to cache by table.
+# TYPE grc_cache_table_bytes_written_total counter
+grc_cache_table_bytes_written_total{cache="my_cache",table="test_users"} %[1]d
+# HELP grc_cache_stage_errors_total Number of failed encode and decode calls of codec stages.
+# TYPE grc_cache_stage_errors_total counter
+grc_cache_stage_errors_total{cache="my_cache",stage="json"} 0
+`, len(data))
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"grc_cache_hits_total", "grc_cache_misses_total", "grc_cache_sets_total", "grc_cache_errors_total",
+		"grc_cache_bytes_written_total", "grc_cache_stale_hits_total", "grc_cache_oversized_total",
+		"grc_cache_table_sets_total", "grc_cache_table_bytes_written_total", "grc_cache_stage_errors_total"))
+
+	// durations vary, check the series and their number of observations
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	counts := make(map[string]uint64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			switch family.GetName() {
+			case "grc_cache_operation_duration_seconds":
+				assert.Equal(t, "my_cache", labels["cache"])
+				counts[labels["op"]] = metric.GetHistogram().GetSampleCount()
+			case "grc_cache_stage_duration_seconds_total":
+				assert.Equal(t, "json", labels["stage"])
+				assert.Greater(t, metric.GetCounter().GetValue(), 0.0)
+			}
+		}
+	}
+	assert.Equal(t, map[string]uint64{"get": 2, "set": 1}, counts)
+}
@@ -0,0 +1,146 @@
+// Package grcs3 provides a grc cache client storing large values in S3-compatible object storage,
+// e.g. multi-megabyte report queries which exceed the value limits of redis
+package grcs3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/minio/minio-go/v7"
+)
+
+// index entry kinds, the first byte of values in the index
+const (
+	inlineEntry byte = 0 // the value follows
+	objectEntry byte = 1 // the name of the object holding the value follows
+)
+
+// Options is a struct for s3 client options
+type Options struct {
+	Bucket    string // bucket of objects, which should have a lifecycle rule expiring objects after the longest ttl of entries
+	Prefix    string // prefix of object names, e.g. "grc/"
+	Threshold int    // values larger than this are stored as objects, smaller values are stored in the index, 0 means 512KB
+}
+
+// Client is a grc.CacheClient storing values larger than Threshold as objects and small index entries in another cache client,
+// which expire with the ttl of Set. Objects are deleted by Del, expired objects should be deleted by a lifecycle rule of the bucket.
+type Client struct {
+	index     grc.CacheClient
+	s3        *minio.Client
+	bucket    string
+	prefix    string
+	threshold int
+}
+
+// NewClient returns a new Client instance storing index entries in index, e.g. a RedisClient, and objects with s3
+func NewClient(index grc.CacheClient, s3 *minio.Client, options Options) *Client {
+	if options.Threshold <= 0 {
+		options.Threshold = 512 << 10
+	}
+	return &Client{
+		index:     index,
+		s3:        s3,
+		bucket:    options.Bucket,
+		prefix:    options.Prefix,
+		threshold: options.Threshold,
+	}
+}
+
+// objectName returns the name of the object of key, keys are hashed to safe object names
+func (c *Client) objectName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return c.prefix + hex.EncodeToString(sum[:])
+}
+
+// Get gets value by key from the index or its object, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	value, err := c.index.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := value.([]byte)
+	if !ok {
+		if s, isString := value.(string); isString {
+			entry = []byte(s)
+		}
+	}
+	if len(entry) == 0 {
+		return nil, grc.ErrCacheMiss // not written by Client
+	}
+
+	switch entry[0] {
+	case inlineEntry:
+		return entry[1:], nil
+	case objectEntry:
+		return c.getObject(ctx, string(entry[1:]))
+	default:
+		return nil, grc.ErrCacheMiss
+	}
+}
+
+// getObject reads an object, a missing object is a miss
+func (c *Client) getObject(ctx context.Context, name string) ([]byte, error) {
+	object, err := c.s3.GetObject(ctx, c.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, grc.ErrCacheMiss
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Set sets value by key with ttl using json encoding, large values are uploaded as objects before their index entry is set
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	if len(data) <= c.threshold {
+		return c.index.Set(ctx, key, append([]byte{inlineEntry}, data...), ttl)
+	}
+
+	name := c.objectName(key)
+	_, err = c.s3.PutObject(ctx, c.bucket, name, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		return err
+	}
+	return c.index.Set(ctx, key, append([]byte{objectEntry}, name...), ttl)
+}
+
+// Del deletes keys from the index and their objects, the index must implement grc.KeyDeleter
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	deleter, ok := c.index.(grc.KeyDeleter)
+	if !ok {
+		return grc.ErrNotSupported
+	}
+	if err := deleter.Del(ctx, keys...); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err := c.s3.RemoveObject(ctx, c.bucket, c.objectName(key), minio.RemoveObjectOptions{})
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNotFound reports whether err is a missing object
+func isNotFound(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
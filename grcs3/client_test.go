@@ -0,0 +1,96 @@
+package grcs3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is an object storage server supporting put, get and delete of objects with path-style urls
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		s.objects[r.URL.Path] = data
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		data, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(string(data)))
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestClient tests storing small values in the index and large values as objects
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	s3 := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	minioClient, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4("", "", ""), // anonymous, so the fake server receives plain bodies
+		Region: "us-east-1",
+	})
+	assert.NoError(t, err)
+
+	index := grc.NewMemoryCache()
+	defer index.Close()
+	client := NewClient(index, minioClient, Options{Bucket: "cache", Prefix: "grc/", Threshold: 8})
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "small", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "large", []byte("0123456789"), time.Minute))
+	assert.Len(t, s3.objects, 1)
+	assert.Contains(t, s3.objects, "/cache/"+client.objectName("large"))
+
+	value, err := client.Get(ctx, "small")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = client.Get(ctx, "large")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("0123456789"), value)
+
+	// a missing object, e.g. expired by a lifecycle rule, is a miss
+	delete(s3.objects, "/cache/"+client.objectName("large"))
+	_, err = client.Get(ctx, "large")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "large", []byte("9876543210"), time.Minute))
+	assert.NoError(t, client.Del(ctx, "large", "small", "missing"))
+	assert.Empty(t, s3.objects)
+	_, err = client.Get(ctx, "large")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+}
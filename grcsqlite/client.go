@@ -0,0 +1,149 @@
+// Package grcsqlite provides a grc cache client storing entries in a SQLite table, for durable caching without redis
+package grcsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evangwt/grc"
+)
+
+// Options is a struct for sqlite client options
+type Options struct {
+	Table          string           // name of the table of entries, created if not exists, default "grc_cache"
+	VacuumInterval time.Duration    // interval of deleting expired entries and vacuuming the database, 0 means 10 minutes, a negative value disables it
+	Now            func() time.Time // returns the current time for expiration, nil means time.Now
+}
+
+// Client is a grc.CacheClient storing entries in a table (key, value, expires_at) of a SQLite database,
+// expires_at is in unix nanoseconds and 0 means no expiration
+type Client struct {
+	db        *sql.DB
+	table     string // quoted table name
+	now       func() time.Time
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewClient returns a new Client instance using db opened with any SQLite driver, creating its table if not exists,
+// db is not closed by Close
+func NewClient(db *sql.DB, options Options) (*Client, error) {
+	table := options.Table
+	if table == "" {
+		table = "grc_cache"
+	}
+	c := &Client{
+		db:    db,
+		table: `"` + strings.ReplaceAll(table, `"`, `""`) + `"`,
+		now:   options.Now,
+		stop:  make(chan struct{}),
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + c.table + ` (key TEXT PRIMARY KEY, value BLOB NOT NULL, expires_at INTEGER NOT NULL)`)
+	if err != nil {
+		return nil, err
+	}
+	index := `"` + strings.ReplaceAll(table+"_expires_at", `"`, `""`) + `"`
+	if _, err = db.Exec(`CREATE INDEX IF NOT EXISTS ` + index + ` ON ` + c.table + ` (expires_at)`); err != nil {
+		return nil, err
+	}
+
+	interval := options.VacuumInterval
+	if interval == 0 {
+		interval = 10 * time.Minute
+	}
+	if interval > 0 {
+		go c.vacuum(interval)
+	}
+	return c, nil
+}
+
+// Get gets value from sqlite by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	var value []byte
+	err := c.db.QueryRowContext(ctx, `SELECT value FROM `+c.table+` WHERE key = ? AND (expires_at = 0 OR expires_at > ?)`,
+		key, c.now().UnixNano()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, grc.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{} // drivers may scan empty blobs as nil
+	}
+	return value, nil
+}
+
+// Set sets value to sqlite by key with ttl using json encoding, ttl <= 0 means no expiration
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl).UnixNano()
+	}
+	_, err = c.db.ExecContext(ctx, `INSERT OR REPLACE INTO `+c.table+` (key, value, expires_at) VALUES (?, ?, ?)`,
+		key, data, expiresAt)
+	return err
+}
+
+// Del deletes keys from sqlite
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+	_, err := c.db.ExecContext(ctx, `DELETE FROM `+c.table+` WHERE key IN (`+placeholders+`)`, args...)
+	return err
+}
+
+// Close stops the background vacuuming
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+	return nil
+}
+
+func (c *Client) vacuum(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			_, _ = c.deleteExpired(context.Background(), true)
+		}
+	}
+}
+
+// deleteExpired deletes expired entries and returns their number, the database is vacuumed if any were deleted and vacuum is set
+func (c *Client) deleteExpired(ctx context.Context, vacuum bool) (int64, error) {
+	result, err := c.db.ExecContext(ctx, `DELETE FROM `+c.table+` WHERE expires_at != 0 AND expires_at <= ?`, c.now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 || !vacuum {
+		return n, err
+	}
+	_, err = c.db.ExecContext(ctx, `VACUUM`)
+	return n, err
+}
@@ -0,0 +1,63 @@
+package grcsqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evangwt/grc"
+	_ "github.com/glebarez/go-sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient tests get, set, expiration, vacuuming and deletion of the sqlite client
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "cache.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	client, err := NewClient(db, Options{Table: "query_cache", VacuumInterval: -1, Now: func() time.Time { return now }})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", map[string]int{"id": 1}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte("C"), time.Second))
+	assert.NoError(t, client.Set(ctx, "c", []byte{}, time.Second)) // replaces the entry
+
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"id":1}`), value)
+
+	value, err = client.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	now = now.Add(2 * time.Second)
+	_, err = client.Get(ctx, "c")
+	assert.ErrorIs(t, err, grc.ErrCacheMiss)
+
+	n, err := client.deleteExpired(ctx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	assert.NoError(t, client.Del(ctx, "a", "b", "missing"))
+	var count int
+	assert.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM query_cache`).Scan(&count))
+	assert.Equal(t, 0, count)
+
+	// the table is reused
+	_, err = NewClient(db, Options{Table: "query_cache", VacuumInterval: -1})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,69 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Pinger is an optional interface of remote cache clients which can check the health of their backend
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthCheck pings the cache client every interval until ctx is done. While a ping fails, the cache is bypassed
+// and queries go straight to the database instead of waiting for the backend to time out on every query.
+// Returns ErrNotSupported if the client is not a Pinger, or wraps a client which is not one.
+func (g *GormCache) HealthCheck(ctx context.Context, interval time.Duration) error {
+	pinger, ok := g.Client().(Pinger)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := pinger.Ping(ctx); errors.Is(err, ErrNotSupported) {
+		return ErrNotSupported
+	}
+	defer atomic.StoreInt32(&g.unhealthy, 0)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		g.ping(ctx, interval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ping pings the cache client with a timeout and records its health
+func (g *GormCache) ping(ctx context.Context, timeout time.Duration) {
+	pinger, ok := g.Client().(Pinger)
+	if !ok {
+		return // the client was replaced
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := pinger.Ping(pingCtx)
+	if ctx.Err() != nil || errors.Is(err, ErrNotSupported) {
+		return // stopped or the client was replaced, not a failure of the backend
+	}
+	unhealthy := int32(0)
+	if err != nil {
+		unhealthy = 1
+	}
+	if old := atomic.SwapInt32(&g.unhealthy, unhealthy); old != unhealthy {
+		if err != nil {
+			logMessage(ctx, g.Config(), LogWarn, "cache backend unhealthy, bypassing cache", err)
+		} else {
+			logMessage(ctx, g.Config(), LogInfo, "cache backend healthy again", nil)
+		}
+	}
+}
+
+// Healthy reports whether the last health check of the cache client succeeded, true without health checks
+func (g *GormCache) Healthy() bool {
+	return atomic.LoadInt32(&g.unhealthy) == 0
+}
@@ -0,0 +1,72 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pingClient is a mapClient whose pings fail while down is set
+type pingClient struct {
+	*mapClient
+	down int32
+}
+
+func (c *pingClient) Ping(ctx context.Context) error {
+	if atomic.LoadInt32(&c.down) == 1 {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+// TestHealthCheck tests bypassing the cache while health checks of the client fail
+func TestHealthCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &pingClient{mapClient: newMapClient()}
+	cache := NewGormCache("health_cache", client, CacheConfig{})
+
+	done := make(chan error)
+	go func() {
+		done <- cache.HealthCheck(ctx, 5*time.Millisecond)
+	}()
+	assert.True(t, cache.Healthy())
+
+	atomic.StoreInt32(&client.down, 1)
+	assert.Eventually(t, func() bool { return !cache.Healthy() }, time.Second, time.Millisecond)
+	atomic.StoreInt32(&client.down, 0)
+	assert.Eventually(t, cache.Healthy, time.Second, time.Millisecond)
+
+	atomic.StoreInt32(&client.down, 1)
+	assert.Eventually(t, func() bool { return !cache.Healthy() }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.True(t, cache.Healthy()) // healthy without health checks
+
+	assert.ErrorIs(t, NewGormCache("my_cache", newMapClient(), CacheConfig{}).HealthCheck(ctx, time.Second), ErrNotSupported)
+}
+
+// TestHealthCheckBypass tests sending queries straight to the database while the client is unhealthy
+func TestHealthCheckBypass(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &pingClient{mapClient: newMapClient(), down: 1}
+	cache := NewGormCache("health_bypass_cache", client, CacheConfig{TTL: time.Minute})
+	assert.NoError(t, db.Use(cache))
+	go cache.HealthCheck(ctx, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return !cache.Healthy() }, time.Second, time.Millisecond)
+
+	var user TestUser
+	assert.NoError(t, Session(db).Where("id = ?", 1).First(&user).Error)
+	assert.Equal(t, int64(0), cache.Stats().Misses)
+	assert.Empty(t, client.values)
+
+	atomic.StoreInt32(&client.down, 0)
+	assert.Eventually(t, cache.Healthy, time.Second, time.Millisecond)
+	assert.NoError(t, Session(db).Where("id = ?", 1).First(&user).Error)
+	assert.Equal(t, int64(1), cache.Stats().Misses)
+	assert.Equal(t, int64(1), cache.Stats().Sets)
+}
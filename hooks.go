@@ -0,0 +1,57 @@
+package grc
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HookEvent is a struct describing a cache operation of a query, passed to lifecycle hooks
+type HookEvent struct {
+	Op       string        // cache operation, load or set
+	Key      string        // cache key
+	SQL      string        // sql of the query, without its variables
+	Table    string        // table of the query, "" for raw queries
+	Duration time.Duration // duration of the cache operation
+	Err      error         // error of a failed operation, nil for other hooks
+}
+
+// Hook is a lifecycle hook called synchronously with the context of the query, so it must not block
+type Hook func(ctx context.Context, event HookEvent)
+
+// hookEvent returns the event of the cache operation op on key started at start
+func hookEvent(db *gorm.DB, op string, key string, start time.Time) HookEvent {
+	return HookEvent{
+		Op:       op,
+		Key:      key,
+		SQL:      db.Statement.SQL.String(),
+		Table:    db.Statement.Table,
+		Duration: time.Since(start),
+	}
+}
+
+// callHook calls hook with event if it is set
+func callHook(db *gorm.DB, hook Hook, event HookEvent) {
+	if hook != nil {
+		hook(db.Statement.Context, event)
+	}
+}
+
+// emitHit logs a cache hit and calls OnHit
+func emitHit(db *gorm.DB, config CacheConfig, event HookEvent) {
+	logEvent(db, config, config.LogLevels.Hit.or(LogDebug), "cache hit", event)
+	callHook(db, config.OnHit, event)
+}
+
+// emitMiss logs a cache miss and calls OnMiss
+func emitMiss(db *gorm.DB, config CacheConfig, event HookEvent) {
+	logEvent(db, config, config.LogLevels.Miss.or(LogDebug), "cache miss", event)
+	callHook(db, config.OnMiss, event)
+}
+
+// emitSet logs a write to cache and calls OnSet
+func emitSet(db *gorm.DB, config CacheConfig, event HookEvent) {
+	logEvent(db, config, config.LogLevels.Set.or(LogDebug), "cache set", event)
+	callHook(db, config.OnSet, event)
+}
@@ -0,0 +1,54 @@
+package grc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHooks tests calling lifecycle hooks on misses, sets, hits and errors
+func TestHooks(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []HookEvent
+		ops    []string
+	)
+	hook := func(name string) Hook {
+		return func(ctx context.Context, event HookEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+			ops = append(ops, name+":"+event.Op)
+		}
+	}
+	client := &downCache{MemoryCache: NewMemoryCache()}
+	defer client.Close()
+	cache := NewGormCache("hooks_cache", client, CacheConfig{
+		TTL:     time.Minute,
+		OnHit:   hook("hit"),
+		OnMiss:  hook("miss"),
+		OnSet:   hook("set"),
+		OnError: hook("error"),
+	})
+	assert.NoError(t, db.Use(cache))
+
+	var user TestUser
+	assert.NoError(t, Session(db).Where("id = ?", 7).First(&user).Error)
+	user = TestUser{}
+	assert.NoError(t, Session(db).Where("id = ?", 7).First(&user).Error)
+	atomic.StoreInt32(&client.down, 1)
+	user = TestUser{}
+	assert.NoError(t, Session(db).Where("id = ?", 7).First(&user).Error)
+
+	assert.Equal(t, []string{"miss:load", "set:set", "hit:load", "error:load", "error:set"}, ops)
+	assert.Equal(t, events[0].Key, events[1].Key)
+	assert.Contains(t, events[0].SQL, "test_users")
+	assert.NotContains(t, events[0].SQL, "7") // without variables
+	assert.Equal(t, "test_users", events[2].Table)
+	assert.Nil(t, events[2].Err)
+	assert.ErrorIs(t, events[3].Err, errDown)
+}
@@ -0,0 +1,186 @@
+package grc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPTTLHeader is the header of the ttl of entries in milliseconds in the http cache protocol, absent or 0 means no expiration
+const HTTPTTLHeader = "X-Cache-TTL"
+
+// HTTPClient is a cache client of the http cache protocol, a simple protocol for caching services behind a proxy:
+//
+//	GET {base}/{key}     200 with the value as body, 404 if the key does not exist
+//	PUT {base}/{key}     sets the body as value with the ttl of the X-Cache-TTL header, 204
+//	DELETE {base}/{key}  deletes the key, 204
+//
+// Keys are path escaped. HTTPHandler serves the protocol with any CacheClient.
+type HTTPClient struct {
+	base   string
+	client *http.Client
+}
+
+// NewHTTPClient returns a new HTTPClient instance of the service at base, e.g. "http://cache.internal/grc",
+// using client, nil means http.DefaultClient
+func NewHTTPClient(base string, client *http.Client) *HTTPClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPClient{base: strings.TrimSuffix(base, "/"), client: client}
+}
+
+// url returns the url of key
+func (h *HTTPClient) url(key string) string {
+	return h.base + "/" + url.PathEscape(key)
+}
+
+// Get gets value from the service by key, returns ErrCacheMiss if the key does not exist
+func (h *HTTPClient) Get(ctx context.Context, key string) (interface{}, error) {
+	resp, err := h.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, ErrCacheMiss
+	default:
+		return nil, httpError(resp)
+	}
+}
+
+// Set sets value to the service by key with ttl using json encoding, []byte values are set as is, ttl <= 0 means no expiration
+func (h *HTTPClient) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := ValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	if ttl > 0 {
+		header.Set(HTTPTTLHeader, strconv.FormatInt(httpTTL(ttl), 10))
+	}
+	resp, err := h.do(ctx, http.MethodPut, key, data, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return httpError(resp)
+	}
+	return nil
+}
+
+// httpTTL converts a positive ttl to milliseconds, rounding up so ttls below a millisecond do not mean no expiration
+func httpTTL(ttl time.Duration) int64 {
+	return int64((ttl + time.Millisecond - 1) / time.Millisecond)
+}
+
+// Del deletes keys from the service, one request per key
+func (h *HTTPClient) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		resp, err := h.do(ctx, http.MethodDelete, key, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+			return httpError(resp)
+		}
+	}
+	return nil
+}
+
+func (h *HTTPClient) do(ctx context.Context, method, key string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, h.url(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return h.client.Do(req)
+}
+
+// httpError returns an error of an unexpected response
+func httpError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("grc: http cache: %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+// HTTPHandler returns a http.Handler serving the http cache protocol of HTTPClient with client,
+// mount it under the base path with http.StripPrefix, e.g. http.Handle("/grc/", http.StripPrefix("/grc", handler))
+func HTTPHandler(client CacheClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/"))
+		if err != nil || key == "" {
+			http.Error(w, "invalid key", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			value, err := client.Get(r.Context(), key)
+			if isCacheMiss(err) {
+				http.NotFound(w, r)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			data, err := ValueBytes(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(data)
+		case http.MethodPut:
+			var ttl time.Duration
+			if s := r.Header.Get(HTTPTTLHeader); s != "" {
+				ms, err := strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					http.Error(w, "invalid "+HTTPTTLHeader, http.StatusBadRequest)
+					return
+				}
+				ttl = time.Duration(ms) * time.Millisecond
+			}
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := client.Set(r.Context(), key, data, ttl); err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			deleter, ok := client.(KeyDeleter)
+			if !ok {
+				http.Error(w, ErrNotSupported.Error(), http.StatusNotImplemented)
+				return
+			}
+			if err := deleter.Del(r.Context(), key); err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
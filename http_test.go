@@ -0,0 +1,66 @@
+package grc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHTTPClient tests the http cache protocol between HTTPClient and HTTPHandler
+func TestHTTPClient(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryCache()
+	defer backend.Close()
+	mux := http.NewServeMux()
+	mux.Handle("/grc/", http.StripPrefix("/grc", HTTPHandler(backend)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := NewHTTPClient(server.URL+"/grc/", nil)
+
+	_, err := client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	key := "grc:users/1?x=%" // escaped in paths
+	assert.NoError(t, client.Set(ctx, key, []byte("A"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", TestUser{ID: 1, Name: "B"}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte{}, time.Second))
+
+	value, err := client.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	ttl, err := backend.TTL(ctx, key)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"ID":1,"Name":"B"}`), value)
+	ttl, err = backend.TTL(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	value, err = client.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	// ttls below a millisecond are rounded up instead of meaning no expiration
+	assert.Equal(t, int64(1), httpTTL(500*time.Microsecond))
+	assert.Equal(t, int64(2), httpTTL(1500*time.Microsecond))
+	assert.NoError(t, client.Set(ctx, "d", []byte("D"), 500*time.Microsecond))
+	time.Sleep(5 * time.Millisecond)
+	_, err = client.Get(ctx, "d")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, client.Del(ctx, key, "missing"))
+	_, err = client.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	// a backend without Del responds 501
+	unsupported := httptest.NewServer(HTTPHandler(newMapClient()))
+	defer unsupported.Close()
+	assert.ErrorContains(t, NewHTTPClient(unsupported.URL, nil).Del(ctx, "a"), "501")
+}
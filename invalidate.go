@@ -0,0 +1,109 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNotSupported is returned when the cache client does not support an operation
+var ErrNotSupported = errors.New("grc: operation not supported by cache client")
+
+// KeyDeleter is an optional interface of cache clients which can delete keys
+type KeyDeleter interface {
+	Del(ctx context.Context, keys ...string) error
+}
+
+// PrefixFlusher is an optional interface of cache clients which can delete all keys under a prefix
+type PrefixFlusher interface {
+	FlushPrefix(ctx context.Context, prefix string) error
+}
+
+// KeyLister is an optional interface of cache clients which can list the keys under a prefix,
+// at most limit keys are returned, limit <= 0 means all keys
+type KeyLister interface {
+	ListKeys(ctx context.Context, prefix string, limit int) ([]string, error)
+}
+
+// InvalidateKey deletes cache entries by keys, e.g. keys returned by a debug log,
+// and publishes the invalidation to other instances if a Broadcaster is configured
+func (g *GormCache) InvalidateKey(ctx context.Context, keys ...string) error {
+	if err := invalidateKeys(ctx, g.Client(), keys); err != nil {
+		return err
+	}
+	return g.publish(ctx, Invalidation{Keys: keys})
+}
+
+// InvalidateTable deletes all cache entries of queries on table, e.g. after out-of-band writes to the table,
+// and publishes the invalidation to other instances if a Broadcaster is configured.
+// Entries are grouped by the table of the statement, joined tables are not tracked.
+func (g *GormCache) InvalidateTable(ctx context.Context, tables ...string) error {
+	if err := invalidateTables(ctx, g.Client(), g.Config(), tables); err != nil {
+		return err
+	}
+	return g.publish(ctx, Invalidation{Tables: tables})
+}
+
+// ListenInvalidations receives the invalidations published by other instances with the configured Broadcaster
+// until ctx is done, and drops their entries from local, e.g. the in-process L1 cache of a TieredCache.
+// A nil local means the client of the cache.
+func (g *GormCache) ListenInvalidations(ctx context.Context, local CacheClient) error {
+	broadcaster := g.Config().Broadcaster
+	if broadcaster == nil {
+		return errors.New("grc: no broadcaster configured")
+	}
+	return broadcaster.Subscribe(ctx, func(msg Invalidation) {
+		client := local
+		if client == nil {
+			client = g.Client()
+		}
+		config := g.Config()
+		if err := invalidateKeys(ctx, client, msg.Keys); err != nil {
+			logMessage(ctx, config, LogWarn, "invalidate keys failed", err)
+		}
+		if err := invalidateTables(ctx, client, config, msg.Tables); err != nil {
+			logMessage(ctx, config, LogWarn, "invalidate tables failed", err)
+		}
+	})
+}
+
+// publish publishes msg with the configured Broadcaster, if any
+func (g *GormCache) publish(ctx context.Context, msg Invalidation) error {
+	broadcaster := g.Config().Broadcaster
+	if broadcaster == nil {
+		return nil
+	}
+	if err := broadcaster.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("grc: publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// invalidateKeys deletes keys from client
+func invalidateKeys(ctx context.Context, client CacheClient, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	deleter, ok := client.(KeyDeleter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return deleter.Del(ctx, keys...)
+}
+
+// invalidateTables deletes all keys of tables from client
+func invalidateTables(ctx context.Context, client CacheClient, config CacheConfig, tables []string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	flusher, ok := client.(PrefixFlusher)
+	if !ok {
+		return ErrNotSupported
+	}
+	for _, table := range tables {
+		if err := flusher.FlushPrefix(ctx, tablePrefix(config, table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package grc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestInvalidate tests invalidating entries by key and by table
+func TestInvalidate(t *testing.T) {
+	ctx := context.Background()
+	cache := NewGormCache("invalidate_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "invalidate:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	var users []TestUser
+	session := db.Session(&gorm.Session{Context: context.WithValue(ctx, UseCacheKey, true)})
+	assert.NoError(t, session.Where("id > ?", 10).Find(&users).Error)
+	assert.NoError(t, session.Where("id > ?", 20).Find(&users).Error)
+
+	keys, err := rdb.Keys(ctx, "invalidate:test_users:*").Result()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	assert.NoError(t, cache.InvalidateKey(ctx, keys[0]))
+	assert.Equal(t, int64(1), rdb.Exists(ctx, keys...).Val())
+
+	assert.NoError(t, cache.InvalidateTable(ctx, "test_users"))
+	assert.Equal(t, int64(0), rdb.Exists(ctx, keys...).Val())
+}
+
+// TestInvalidateNotSupported tests invalidating with a client without delete support
+func TestInvalidateNotSupported(t *testing.T) {
+	cache := NewGormCache("my_cache", newMapClient(), CacheConfig{})
+	assert.ErrorIs(t, cache.InvalidateKey(context.Background(), "key"), ErrNotSupported)
+	assert.ErrorIs(t, cache.InvalidateTable(context.Background(), "users"), ErrNotSupported)
+}
+
+// TestEscapePattern tests escaping redis glob-style patterns
+func TestEscapePattern(t *testing.T) {
+	assert.Equal(t, `cache:users:`, escapePattern("cache:users:"))
+	assert.Equal(t, `a\*b\?c\[d\]\\`, escapePattern(`a*b?c[d]\`))
+}
+
+// fakeBus delivers invalidations between fakeBroadcasters in process
+type fakeBus struct {
+	mu       sync.Mutex
+	handlers map[*fakeBroadcaster]func(msg Invalidation)
+}
+
+type fakeBroadcaster struct {
+	bus *fakeBus
+}
+
+func (b *fakeBroadcaster) Publish(ctx context.Context, msg Invalidation) error {
+	b.bus.mu.Lock()
+	defer b.bus.mu.Unlock()
+	for other, handler := range b.bus.handlers {
+		if other != b {
+			handler(msg)
+		}
+	}
+	return nil
+}
+
+func (b *fakeBroadcaster) Subscribe(ctx context.Context, handler func(msg Invalidation)) error {
+	b.bus.mu.Lock()
+	b.bus.handlers[b] = handler
+	b.bus.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// prefixCache is a memory cache recording flushed prefixes
+type prefixCache struct {
+	*MemoryCache
+	mu       sync.Mutex
+	prefixes []string
+}
+
+func (c *prefixCache) FlushPrefix(ctx context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prefixes = append(c.prefixes, prefix)
+	return nil
+}
+
+// TestInvalidateBroadcast tests dropping local entries on invalidations published by other instances
+func TestInvalidateBroadcast(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := &fakeBus{handlers: make(map[*fakeBroadcaster]func(msg Invalidation))}
+
+	localA := &prefixCache{MemoryCache: NewMemoryCache()}
+	defer localA.Close()
+	localB := &prefixCache{MemoryCache: NewMemoryCache()}
+	defer localB.Close()
+	a := NewGormCache("a", localA, CacheConfig{Prefix: "a:", Broadcaster: &fakeBroadcaster{bus: bus}})
+	b := NewGormCache("b", localB, CacheConfig{Prefix: "b:", Broadcaster: &fakeBroadcaster{bus: bus}})
+	go a.ListenInvalidations(ctx, nil)
+	go b.ListenInvalidations(ctx, nil)
+	assert.Eventually(t, func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return len(bus.handlers) == 2
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, localA.Set(ctx, "key", []byte("A"), time.Minute))
+	assert.NoError(t, localB.Set(ctx, "key", []byte("B"), time.Minute))
+	assert.NoError(t, a.InvalidateKey(ctx, "key"))
+	_, err := localA.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = localB.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	// tables are flushed with the prefix of each instance
+	assert.NoError(t, a.InvalidateTable(ctx, "users"))
+	assert.Equal(t, []string{"a:users:"}, localA.prefixes)
+	assert.Equal(t, []string{"b:users:"}, localB.prefixes)
+
+	// without a broadcaster
+	assert.Error(t, NewGormCache("c", localA, CacheConfig{}).ListenInvalidations(ctx, nil))
+}
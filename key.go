@@ -0,0 +1,175 @@
+package grc
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// hashQuery hashes sql and its vars, vars are written with their types instead of explained into the sql,
+// as explained sql renders binary vars as <binary> and times with millisecond precision.
+// A non-empty instance isolates the keys of databases sharing a cache.
+func hashQuery(instance, sql string, vars []interface{}) string {
+	h := sha256.New()
+	if instance != "" {
+		h.Write([]byte(instance))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(sql))
+	for _, v := range vars {
+		h.Write([]byte{0})
+		writeVar(h, v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeVar(h hash.Hash, v interface{}) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() != reflect.Ptr || !rv.IsNil() {
+			value, err := valuer.Value()
+			if err != nil {
+				fmt.Fprintf(h, "error:%v", err)
+				return
+			}
+			v = value
+		}
+	}
+
+	switch value := v.(type) {
+	case nil:
+		h.Write([]byte("nil"))
+	case string:
+		h.Write([]byte("string:" + strconv.Quote(value)))
+	case []byte:
+		h.Write([]byte("bytes:" + hex.EncodeToString(value)))
+	case time.Time:
+		h.Write([]byte("time:" + value.Format(time.RFC3339Nano)))
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				h.Write([]byte("nil"))
+			} else {
+				writeVar(h, rv.Elem().Interface())
+			}
+			return
+		}
+		fmt.Fprintf(h, "%T:%#v", v, v)
+	}
+}
+
+// instanceID returns the identity of the database of a query, the configured InstanceID or the host, port
+// and database name of the dsn of the dialector
+func instanceID(db *gorm.DB, config CacheConfig) string {
+	if config.InstanceID != "" {
+		return config.InstanceID
+	}
+	return dsnIdentity(dialectorDSN(db.Dialector))
+}
+
+// keyValueDSN matches dsns in the key=value format of postgres, e.g. "host=db1 port=5432 dbname=app"
+var keyValueDSN = regexp.MustCompile(`^\s*\w+\s*=`)
+
+// dsnIdentity returns the host, port and database name of dsn without credentials and options,
+// so rotating a password or reordering options does not move the keys of the database.
+// The postgres key=value format, urls like postgres:// or sqlserver:// and the mysql format
+// user:password@tcp(host:port)/dbname are supported, other dsns like sqlite file names lose their query.
+func dsnIdentity(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	if keyValueDSN.MatchString(dsn) {
+		fields := parseKeyValueDSN(dsn)
+		host := fields["host"]
+		if port := fields["port"]; port != "" {
+			host += ":" + port
+		}
+		return host + "/" + fields["dbname"]
+	}
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil {
+			dbname := strings.TrimPrefix(u.Path, "/")
+			if dbname == "" {
+				dbname = u.Query().Get("database") // sqlserver
+			}
+			return u.Scheme + "://" + u.Host + "/" + dbname
+		}
+	}
+
+	if i := strings.IndexByte(dsn, '?'); i >= 0 {
+		dsn = dsn[:i]
+	}
+	end := strings.LastIndexByte(dsn, '/')
+	if end < 0 {
+		end = len(dsn)
+	}
+	if at := strings.LastIndexByte(dsn[:end], '@'); at >= 0 {
+		dsn = dsn[at+1:] // user:password@
+	}
+	return dsn
+}
+
+// parseKeyValueDSN parses a dsn in the key=value format, values may be quoted with single quotes
+func parseKeyValueDSN(dsn string) map[string]string {
+	fields := make(map[string]string)
+	for dsn = strings.TrimSpace(dsn); dsn != ""; dsn = strings.TrimSpace(dsn) {
+		eq := strings.IndexByte(dsn, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(dsn[:eq])
+		dsn = strings.TrimLeft(dsn[eq+1:], " \t")
+
+		var value strings.Builder
+		if strings.HasPrefix(dsn, "'") {
+			i := 1
+			for ; i < len(dsn) && dsn[i] != '\''; i++ {
+				if dsn[i] == '\\' && i+1 < len(dsn) {
+					i++
+				}
+				value.WriteByte(dsn[i])
+			}
+			if i < len(dsn) {
+				i++ // closing quote
+			}
+			dsn = dsn[i:]
+		} else {
+			end := strings.IndexAny(dsn, " \t\n")
+			if end < 0 {
+				end = len(dsn)
+			}
+			value.WriteString(dsn[:end])
+			dsn = dsn[end:]
+		}
+		fields[key] = value.String()
+	}
+	return fields
+}
+
+// dialectorDSN returns the DSN field of a dialector, e.g. postgres, mysql and sqlite dialectors,
+// or "" if the dialector has none, e.g. it is opened with an existing connection
+func dialectorDSN(dialector gorm.Dialector) string {
+	rv := reflect.Indirect(reflect.ValueOf(dialector))
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	field, ok := rv.Type().FieldByName("DSN")
+	if !ok || field.Type.Kind() != reflect.String {
+		return ""
+	}
+	dsn, err := rv.FieldByIndexErr(field.Index) // the embedded config may be a nil pointer
+	if err != nil {
+		return ""
+	}
+	return dsn.String()
+}
@@ -0,0 +1,63 @@
+package grc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// KeyspaceListener listens to redis keyspace notifications of keys under a prefix,
+// so keys expired or deleted outside grc can be evicted from local caches.
+//
+// Keyspace notifications are disabled by default, they must be enabled on the redis server
+// with `CONFIG SET notify-keyspace-events Egxe`, E for keyevent channels, g for deletes, x for expirations
+// and e for maxmemory evictions.
+type KeyspaceListener struct {
+	client  *redis.Client
+	prefix  string
+	onEvict func(key string)
+}
+
+// NewKeyspaceListener returns a new KeyspaceListener instance, onEvict is called with the key of every expired,
+// evicted or deleted key under prefix
+func NewKeyspaceListener(client *redis.Client, prefix string, onEvict func(key string)) *KeyspaceListener {
+	return &KeyspaceListener{
+		client:  client,
+		prefix:  prefix,
+		onEvict: onEvict,
+	}
+}
+
+// Listen subscribes to keyspace notifications and dispatches them until ctx is done
+func (l *KeyspaceListener) Listen(ctx context.Context) error {
+	db := l.client.Options().DB
+	pubsub := l.client.Subscribe(ctx,
+		fmt.Sprintf("__keyevent@%d__:expired", db),
+		fmt.Sprintf("__keyevent@%d__:evicted", db),
+		fmt.Sprintf("__keyevent@%d__:del", db),
+	)
+	defer pubsub.Close()
+
+	// wait for subscription confirmation
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			// the payload of keyevent notifications is the key name
+			if strings.HasPrefix(msg.Payload, l.prefix) {
+				l.onEvict(msg.Payload)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestKeyspaceListener tests receiving deletion of keys under the prefix
+func TestKeyspaceListener(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.NoError(t, rdb.ConfigSet(ctx, "notify-keyspace-events", "Egxe").Err())
+
+	evicted := make(chan string, 1)
+	listener := NewKeyspaceListener(rdb, "keyspace:", func(key string) {
+		evicted <- key
+	})
+	go listener.Listen(ctx)
+	time.Sleep(100 * time.Millisecond) // wait for subscription
+
+	assert.NoError(t, rdb.Set(ctx, "other:a", "A", time.Minute).Err())
+	assert.NoError(t, rdb.Del(ctx, "other:a").Err())
+	assert.NoError(t, rdb.Set(ctx, "keyspace:a", "A", time.Minute).Err())
+	assert.NoError(t, rdb.Del(ctx, "keyspace:a").Err())
+
+	select {
+	case key := <-evicted:
+		assert.Equal(t, "keyspace:a", key)
+	case <-time.After(time.Second):
+		t.Fatal("no keyspace notification received")
+	}
+}
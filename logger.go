@@ -0,0 +1,89 @@
+package grc
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LogLevel is the level of a log record
+type LogLevel int
+
+const (
+	LogDefault LogLevel = iota // the default level of the record
+	LogDebug
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// Logger is an interface for structured logging of the cache layer, NewSlogLogger adapts a *slog.Logger.
+// args are alternating keys and values.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, args ...interface{})
+}
+
+// LogLevels is a struct for the levels of query records of a Logger
+type LogLevels struct {
+	Hit   LogLevel // level of cache hits, default LogDebug
+	Miss  LogLevel // level of cache misses, default LogDebug
+	Set   LogLevel // level of writes to cache, default LogDebug
+	Error LogLevel // level of failed cache operations, default LogError
+}
+
+// or returns l, or def if l is LogDefault
+func (l LogLevel) or(def LogLevel) LogLevel {
+	if l == LogDefault {
+		return def
+	}
+	return l
+}
+
+// logEvent logs a cache event of a query with the key, table, operation, latency and error if a Logger is configured
+func logEvent(db *gorm.DB, config CacheConfig, level LogLevel, msg string, event HookEvent) {
+	if config.Logger == nil {
+		return
+	}
+	args := []interface{}{"op", event.Op, "key", event.Key, "table", event.Table, "latency", event.Duration}
+	if event.Err != nil {
+		args = append(args, "error", event.Err)
+	}
+	config.Logger.Log(db.Statement.Context, level, msg, args...)
+}
+
+// logMessage logs a message of the cache layer and its error, if any, with the configured Logger or the standard logger
+func logMessage(ctx context.Context, config CacheConfig, level LogLevel, msg string, err error) {
+	logWith(ctx, config.Logger, level, msg, err)
+}
+
+// logWith logs a message and its error, if any, with logger or the standard logger if logger is nil,
+// for components without a cache config
+func logWith(ctx context.Context, logger Logger, level LogLevel, msg string, err error) {
+	switch {
+	case logger != nil && err != nil:
+		logger.Log(ctx, level, msg, "error", err)
+	case logger != nil:
+		logger.Log(ctx, level, msg)
+	case err != nil:
+		log.Printf("%s: %v", msg, err)
+	default:
+		log.Print(msg)
+	}
+}
+
+// debugQuery logs the key, cache decision, outcome and ttl of a query in debug mode
+func (g *GormCache) debugQuery(db *gorm.DB, config CacheConfig, key string, reason string, result string) {
+	var ttl time.Duration
+	if key != "" {
+		ttl = g.cacheTTL(db, config)
+	}
+	if config.Logger != nil {
+		config.Logger.Log(db.Statement.Context, LogDebug, "cache query", "cache", g.name, "key", key,
+			"sql", db.Statement.SQL.String(), "cached", key != "", "reason", reason, "result", result, "ttl", ttl)
+		return
+	}
+	log.Printf("grc debug: cache: %s, key: %q, sql: %s, cached: %v, reason: %q, result: %s, ttl: %v",
+		g.name, key, db.Statement.SQL.String(), key != "", reason, result, ttl)
+}
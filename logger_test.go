@@ -0,0 +1,113 @@
+package grc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordLogger is a Logger keeping records as maps of their message, level and args
+type recordLogger struct {
+	mu      sync.Mutex
+	records []map[string]interface{}
+}
+
+func (l *recordLogger) Log(ctx context.Context, level LogLevel, msg string, args ...interface{}) {
+	record := map[string]interface{}{"msg": msg, "level": level}
+	for i := 0; i+1 < len(args); i += 2 {
+		record[args[i].(string)] = args[i+1]
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, record)
+}
+
+// TestDebug tests logging the key, decision, outcome and ttl of queries in debug mode
+func TestDebug(t *testing.T) {
+	logger := &recordLogger{}
+	cache := NewGormCache("debug_cache", newMapClient(), CacheConfig{
+		TTL:           time.Minute,
+		ExcludeTables: []string{"orders"},
+		Logger:        logger,
+		LogLevels:     LogLevels{Hit: LogInfo, Miss: LogInfo, Set: LogInfo},
+		Debug:         true,
+	})
+	assert.NoError(t, db.Use(cache))
+
+	var user TestUser
+	assert.NoError(t, db.Where("id = ?", 5).First(&user).Error)
+	user = TestUser{}
+	assert.NoError(t, Session(db).Where("id = ?", 5).First(&user).Error)
+	user = TestUser{}
+	assert.NoError(t, Session(db).Where("id = ?", 5).First(&user).Error)
+
+	var debug []map[string]interface{}
+	for _, record := range logger.records {
+		if record["msg"] == "cache query" {
+			debug = append(debug, record)
+		}
+	}
+	assert.Len(t, debug, 3)
+	assert.Equal(t, false, debug[0]["cached"])
+	assert.Equal(t, "cache not used by the query", debug[0]["reason"])
+	assert.Equal(t, "skip", debug[0]["result"])
+	assert.Equal(t, "miss", debug[1]["result"])
+	assert.Equal(t, time.Minute, debug[1]["ttl"])
+	assert.Equal(t, "hit", debug[2]["result"])
+	assert.Equal(t, debug[1]["key"], debug[2]["key"])
+	assert.Equal(t, LogDebug, debug[2]["level"])
+	assert.Contains(t, debug[2]["sql"], "test_users")
+}
+
+// TestCacheDecision tests the reasons of queries not being cached
+func TestCacheDecision(t *testing.T) {
+	cache := NewGormCache("my_cache", newMapClient(), CacheConfig{ExcludeTables: []string{"test_users"}})
+	stmt := Session(db).Model(&TestUser{}).Where("id = ?", 1)
+	stmt.Statement.Table = "test_users"
+
+	enabled, reason := cache.cacheDecision(stmt, cache.Config())
+	assert.False(t, enabled)
+	assert.Equal(t, "table test_users excluded", reason)
+
+	cache.UpdateConfig(CacheConfig{})
+	enabled, reason = cache.cacheDecision(stmt, cache.Config())
+	assert.True(t, enabled)
+	assert.Empty(t, reason)
+
+	cache.Disable()
+	_, reason = cache.cacheDecision(stmt, cache.Config())
+	assert.Equal(t, "cache disabled", reason)
+}
+
+// failingStage is a Stage failing every transformation
+type failingStage struct{}
+
+func (failingStage) Name() string                       { return "failing" }
+func (failingStage) Encode(data []byte) ([]byte, error) { return nil, errDown }
+func (failingStage) Decode(data []byte) ([]byte, error) { return nil, errDown }
+
+// TestLoggerBackground tests logging invalid ttls and failed background writes with a Logger
+func TestLoggerBackground(t *testing.T) {
+	logger := &recordLogger{}
+	_, ok := contextTTL(context.WithValue(context.Background(), CacheTTLKey, "soon"), CacheConfig{Logger: logger})
+	assert.False(t, ok)
+
+	writer := NewBatchWriter(Chain(newMapClient(), WithStage(failingStage{})), BatchWriterOptions{Logger: logger, Interval: time.Millisecond})
+	defer writer.Close()
+	assert.NoError(t, writer.Set(context.Background(), "a", []byte("A"), time.Minute))
+
+	msgs := func() []interface{} {
+		logger.mu.Lock()
+		defer logger.mu.Unlock()
+		var msgs []interface{}
+		for _, record := range logger.records {
+			msgs = append(msgs, record["msg"])
+		}
+		return msgs
+	}
+	assert.Eventually(t, func() bool { return len(msgs()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []interface{}{"invalid cache ttl", "batch write failed"}, msgs())
+}
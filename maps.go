@@ -0,0 +1,162 @@
+package grc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// typedValue is a map value with its type, json and other codecs lose the types of interface{} values,
+// e.g. int64 becomes float64 and time.Time becomes string, so map destinations are cached with types
+type typedValue struct {
+	T string // value type
+	V string // value formatted as string
+}
+
+// mapEntry is the cached value of a map destination
+type mapEntry struct {
+	Rows []map[string]typedValue
+}
+
+// mapRows returns the rows of a map destination
+func mapRows(dest interface{}) ([]map[string]interface{}, bool) {
+	switch d := dest.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{d}, true
+	case *map[string]interface{}:
+		if *d == nil {
+			return nil, true
+		}
+		return []map[string]interface{}{*d}, true
+	case *[]map[string]interface{}:
+		return *d, true
+	default:
+		return nil, false
+	}
+}
+
+// encodeMapRows converts rows of a map destination into a mapEntry
+func encodeMapRows(rows []map[string]interface{}) (mapEntry, error) {
+	entry := mapEntry{Rows: make([]map[string]typedValue, 0, len(rows))}
+	for _, row := range rows {
+		typed := make(map[string]typedValue, len(row))
+		for column, value := range row {
+			v, err := encodeTypedValue(value)
+			if err != nil {
+				return entry, fmt.Errorf("column %s: %w", column, err)
+			}
+			typed[column] = v
+		}
+		entry.Rows = append(entry.Rows, typed)
+	}
+	return entry, nil
+}
+
+// decodeMapRows scans a mapEntry into a map destination, returns the number of rows
+func decodeMapRows(entry mapEntry, dest interface{}) (int64, error) {
+	rows := make([]map[string]interface{}, 0, len(entry.Rows))
+	for _, typed := range entry.Rows {
+		row := make(map[string]interface{}, len(typed))
+		for column, v := range typed {
+			value, err := decodeTypedValue(v)
+			if err != nil {
+				return 0, fmt.Errorf("column %s: %w", column, err)
+			}
+			row[column] = value
+		}
+		rows = append(rows, row)
+	}
+
+	switch d := dest.(type) {
+	case map[string]interface{}:
+		if len(rows) > 0 {
+			for column, value := range rows[0] {
+				d[column] = value
+			}
+		}
+	case *map[string]interface{}:
+		if len(rows) > 0 {
+			*d = rows[0]
+		}
+	case *[]map[string]interface{}:
+		*d = rows
+	}
+	return int64(len(rows)), nil
+}
+
+func encodeTypedValue(value interface{}) (typedValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return typedValue{T: "nil"}, nil
+	case bool:
+		return typedValue{T: "bool", V: strconv.FormatBool(v)}, nil
+	case int:
+		return typedValue{T: "int", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int16:
+		return typedValue{T: "int16", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int32:
+		return typedValue{T: "int32", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int64:
+		return typedValue{T: "int64", V: strconv.FormatInt(v, 10)}, nil
+	case uint64:
+		return typedValue{T: "uint64", V: strconv.FormatUint(v, 10)}, nil
+	case float32:
+		return typedValue{T: "float32", V: strconv.FormatFloat(float64(v), 'g', -1, 32)}, nil
+	case float64:
+		return typedValue{T: "float64", V: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case string:
+		return typedValue{T: "string", V: v}, nil
+	case []byte:
+		return typedValue{T: "bytes", V: base64.StdEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return typedValue{T: "time", V: v.Format(time.RFC3339Nano)}, nil
+	default:
+		// other types are cached as json, and read back as their json counterparts
+		data, err := json.Marshal(v)
+		if err != nil {
+			return typedValue{}, err
+		}
+		return typedValue{T: "json", V: string(data)}, nil
+	}
+}
+
+func decodeTypedValue(v typedValue) (interface{}, error) {
+	switch v.T {
+	case "nil":
+		return nil, nil
+	case "bool":
+		return strconv.ParseBool(v.V)
+	case "int":
+		i, err := strconv.ParseInt(v.V, 10, 0)
+		return int(i), err
+	case "int16":
+		i, err := strconv.ParseInt(v.V, 10, 16)
+		return int16(i), err
+	case "int32":
+		i, err := strconv.ParseInt(v.V, 10, 32)
+		return int32(i), err
+	case "int64":
+		return strconv.ParseInt(v.V, 10, 64)
+	case "uint64":
+		return strconv.ParseUint(v.V, 10, 64)
+	case "float32":
+		f, err := strconv.ParseFloat(v.V, 32)
+		return float32(f), err
+	case "float64":
+		return strconv.ParseFloat(v.V, 64)
+	case "string":
+		return v.V, nil
+	case "bytes":
+		return base64.StdEncoding.DecodeString(v.V)
+	case "time":
+		return time.Parse(time.RFC3339Nano, v.V)
+	case "json":
+		var value interface{}
+		err := json.Unmarshal([]byte(v.V), &value)
+		return value, err
+	default:
+		return nil, fmt.Errorf("unknown value type %q", v.T)
+	}
+}
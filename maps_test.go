@@ -0,0 +1,66 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestMapRows tests caching map destinations with their value types
+func TestMapRows(t *testing.T) {
+	cache := NewGormCache("my_cache", nil, CacheConfig{})
+	now := time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)
+	rows := []map[string]interface{}{
+		{"id": int64(1), "name": "A", "score": 1.5, "active": true, "created_at": now, "data": []byte{1, 2}, "deleted_at": nil},
+		{"id": int32(2), "small": int16(3), "ratio": float32(0.5)},
+	}
+
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}, MsgpackCodec{}} {
+		config := CacheConfig{Codec: codec}
+
+		entry, err := encodeMapRows(rows)
+		assert.NoError(t, err)
+		data, err := cache.encode(config, "key", entry)
+		assert.NoError(t, err)
+
+		var decoded mapEntry
+		assert.NoError(t, cache.decode(config, "key", data, &decoded))
+
+		var dest []map[string]interface{}
+		n, err := decodeMapRows(decoded, &dest)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		assert.Equal(t, rows, dest, codec.Name())
+
+		single := map[string]interface{}{}
+		_, err = decodeMapRows(decoded, single)
+		assert.NoError(t, err)
+		assert.Equal(t, rows[0], single, codec.Name())
+	}
+}
+
+// TestCachePluckAndMaps tests caching Pluck and map destinations
+func TestCachePluckAndMaps(t *testing.T) {
+	cache := NewGormCache("maps_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "maps:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	for i := 0; i < 2; i++ {
+		var names []string
+		assert.NoError(t, db.Session(&gorm.Session{Context: ctx}).Model(&TestUser{}).Where("id <= ?", 3).Order("id").Pluck("name", &names).Error)
+		assert.Equal(t, []string{"41", "42", "43"}, names)
+
+		var users []map[string]interface{}
+		result := db.Session(&gorm.Session{Context: ctx}).Model(&TestUser{}).Where("id <= ?", 3).Order("id").Find(&users)
+		assert.NoError(t, result.Error)
+		assert.Equal(t, int64(3), result.RowsAffected)
+		assert.EqualValues(t, 1, users[0]["id"]) // the integer type depends on the driver
+		assert.Equal(t, "41", users[0]["name"])
+	}
+}
@@ -0,0 +1,457 @@
+package grc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrMemcachedKey is returned for keys memcached does not accept, longer than 250 bytes or with whitespace or control characters
+var ErrMemcachedKey = errors.New("grc: invalid memcached key")
+
+// ErrMemcachedPoolExhausted is returned when MaxActiveConns connections are in use and the client does not wait
+var ErrMemcachedPoolExhausted = errors.New("grc: memcached connection pool exhausted")
+
+// memcachedRelativeExpiration is the longest exptime memcached treats as relative, longer exptimes are unix timestamps
+const memcachedRelativeExpiration = 30 * 24 * time.Hour
+
+// memcachedGetBatch is the maximum number of keys of one get command
+const memcachedGetBatch = 100
+
+// backoff bounds of dialing after dial failures
+const (
+	memcachedMinBackoff = 50 * time.Millisecond
+	memcachedMaxBackoff = 5 * time.Second
+)
+
+// MemcachedClient is a dependency-free memcached client using the text protocol
+type MemcachedClient struct {
+	addr    string
+	timeout time.Duration
+	dialer  net.Dialer
+	mu      sync.Mutex
+	idle    []*memcachedConn
+	maxIdle int
+	active  chan struct{} // a slot per connection in use, nil means no limit
+	wait    bool
+	closed  bool
+	dialErr error         // last dial error, returned without dialing until retryAt
+	retryAt time.Time     // time of the next dial after a dial failure
+	backoff time.Duration // current backoff, doubled on each dial failure
+}
+
+// MemcachedOptions is a struct for memcached client options
+type MemcachedOptions struct {
+	Timeout        time.Duration // timeout of dialing and of each operation without a context deadline, 0 means a second
+	MaxIdleConns   int           // maximum number of idle connections kept for reuse, 0 means 2
+	MaxActiveConns int           // maximum number of connections open at once, 0 means no limit
+	Wait           bool          // wait for a connection when MaxActiveConns are in use until the context is done, otherwise return ErrMemcachedPoolExhausted
+}
+
+type memcachedConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// NewMemcachedClient returns a new MemcachedClient instance connecting to addr, host:port
+func NewMemcachedClient(addr string, options MemcachedOptions) *MemcachedClient {
+	if options.Timeout <= 0 {
+		options.Timeout = time.Second
+	}
+	if options.MaxIdleConns <= 0 {
+		options.MaxIdleConns = 2
+	}
+	m := &MemcachedClient{
+		addr:    addr,
+		timeout: options.Timeout,
+		dialer:  net.Dialer{Timeout: options.Timeout},
+		maxIdle: options.MaxIdleConns,
+		wait:    options.Wait,
+	}
+	if options.MaxActiveConns > 0 {
+		m.active = make(chan struct{}, options.MaxActiveConns)
+	}
+	return m
+}
+
+// Get gets value from memcached by key, returns ErrCacheMiss if the key does not exist
+func (m *MemcachedClient) Get(ctx context.Context, key string) (interface{}, error) {
+	values, err := m.GetMulti(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == nil {
+		return nil, ErrCacheMiss
+	}
+	return values[0], nil
+}
+
+// GetMulti gets values from memcached by keys with multi-key gets pipelined in one round trip,
+// the values are in the order of keys and nil for missing keys
+func (m *MemcachedClient) GetMulti(ctx context.Context, keys ...string) ([]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	found := make(map[string][]byte, len(keys))
+	err := m.do(ctx, keys, func(c *memcachedConn) error {
+		for i := 0; i < len(keys); i += memcachedGetBatch {
+			batch := keys[i:]
+			if len(batch) > memcachedGetBatch {
+				batch = batch[:memcachedGetBatch]
+			}
+			c.rw.WriteString("get")
+			for _, key := range batch {
+				c.rw.WriteString(" ")
+				c.rw.WriteString(key)
+			}
+			c.rw.WriteString("\r\n")
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+
+		for i := 0; i < len(keys); i += memcachedGetBatch {
+			if err := readMemcachedValues(c.rw.Reader, found); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if value, ok := found[key]; ok {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
+
+// Set sets value to memcached by key with ttl using json encoding, []byte values are set as is, ttl <= 0 means no expiration.
+// ttls are rounded up to whole seconds.
+func (m *MemcachedClient) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return m.SetMulti(ctx, map[string]interface{}{key: value}, ttl)
+}
+
+// SetMulti sets values to memcached by keys with ttl, the sets are pipelined in one round trip
+func (m *MemcachedClient) SetMulti(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(values))
+	data := make([][]byte, 0, len(values))
+	for key, value := range values {
+		v, err := ValueBytes(value)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		data = append(data, v)
+	}
+
+	exptime := memcachedExptime(ttl, time.Now())
+	return m.do(ctx, keys, func(c *memcachedConn) error {
+		for i, key := range keys {
+			fmt.Fprintf(c.rw, "set %s 0 %d %d\r\n", key, exptime, len(data[i]))
+			c.rw.Write(data[i])
+			c.rw.WriteString("\r\n")
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		for range keys {
+			if err := expectMemcachedLine(c.rw.Reader, "STORED"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Del deletes keys from memcached, the deletes are pipelined in one round trip
+func (m *MemcachedClient) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return m.do(ctx, keys, func(c *memcachedConn) error {
+		for _, key := range keys {
+			fmt.Fprintf(c.rw, "delete %s\r\n", key)
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		for range keys {
+			line, err := readMemcachedLine(c.rw.Reader)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(line, []byte("DELETED")) && !bytes.Equal(line, []byte("NOT_FOUND")) {
+				return fmt.Errorf("grc: unexpected memcached response: %q", line)
+			}
+		}
+		return nil
+	})
+}
+
+// Ping checks memcached with a version command
+func (m *MemcachedClient) Ping(ctx context.Context) error {
+	return m.do(ctx, nil, func(c *memcachedConn) error {
+		if _, err := c.rw.WriteString("version\r\n"); err != nil {
+			return err
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readMemcachedLine(c.rw.Reader)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(line, []byte("VERSION")) {
+			return fmt.Errorf("grc: unexpected memcached response: %q", line)
+		}
+		return nil
+	})
+}
+
+// Close closes idle connections, connections in use are closed when released
+func (m *MemcachedClient) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for _, c := range m.idle {
+		c.conn.Close()
+	}
+	m.idle = nil
+	return nil
+}
+
+// memcachedExptime converts ttl to a memcached exptime, ttls over 30 days are converted to unix timestamps
+func memcachedExptime(ttl time.Duration, now time.Time) int64 {
+	if ttl <= 0 {
+		return 0 // no expiration
+	}
+	if ttl > memcachedRelativeExpiration {
+		return now.Add(ttl).Unix()
+	}
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// validMemcachedKey reports whether memcached accepts key
+func validMemcachedKey(key string) bool {
+	if len(key) == 0 || len(key) > 250 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// do runs f with a connection of the pool, retrying once on a new connection if a reused connection was closed by the server
+func (m *MemcachedClient) do(ctx context.Context, keys []string, f func(c *memcachedConn) error) error {
+	for _, key := range keys {
+		if !validMemcachedKey(key) {
+			return ErrMemcachedKey
+		}
+	}
+	if err := m.acquire(ctx); err != nil {
+		return err
+	}
+	defer m.releaseSlot()
+
+	c, reused, err := m.conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = m.run(ctx, c, f)
+	if reused && brokenConn(err) {
+		// the server may have closed the idle connection, all operations are idempotent so retry once on a new connection
+		if c, err = m.dial(ctx); err != nil {
+			return err
+		}
+		err = m.run(ctx, c, f)
+	}
+	return err
+}
+
+// run runs f with a connection, which is released unless f fails with an error other than ErrCacheMiss
+func (m *MemcachedClient) run(ctx context.Context, c *memcachedConn, f func(c *memcachedConn) error) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(m.timeout)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		c.conn.Close()
+		return err
+	}
+
+	err := f(c)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		c.conn.Close() // the state of the connection is unknown
+		return err
+	}
+	m.release(c)
+	return err
+}
+
+// acquire takes a slot for a connection in use, waiting for one if MaxActiveConns are in use and Wait is set
+func (m *MemcachedClient) acquire(ctx context.Context) error {
+	if m.active == nil {
+		return nil
+	}
+	if !m.wait {
+		select {
+		case m.active <- struct{}{}:
+			return nil
+		default:
+			return ErrMemcachedPoolExhausted
+		}
+	}
+	select {
+	case m.active <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseSlot gives back the slot taken by acquire
+func (m *MemcachedClient) releaseSlot() {
+	if m.active != nil {
+		<-m.active
+	}
+}
+
+// conn returns an idle connection or dials a new one, a connection is only dialed without idle connections,
+// so at most as many connections as slots are open
+func (m *MemcachedClient) conn(ctx context.Context) (c *memcachedConn, reused bool, err error) {
+	m.mu.Lock()
+	if n := len(m.idle); n > 0 {
+		c := m.idle[n-1]
+		m.idle = m.idle[:n-1]
+		m.mu.Unlock()
+		return c, true, nil
+	}
+	m.mu.Unlock()
+
+	c, err = m.dial(ctx)
+	return c, false, err
+}
+
+// dial dials a new connection, after a dial failure the error is returned without dialing
+// until a jittered backoff, doubled on each failure, has passed
+func (m *MemcachedClient) dial(ctx context.Context) (*memcachedConn, error) {
+	m.mu.Lock()
+	if m.dialErr != nil && time.Now().Before(m.retryAt) {
+		err := m.dialErr
+		m.mu.Unlock()
+		return nil, err
+	}
+	m.mu.Unlock()
+
+	conn, err := m.dialer.DialContext(ctx, "tcp", m.addr)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		if ctx.Err() == nil { // the server is unreachable rather than the caller gone
+			m.backoff *= 2
+			if m.backoff < memcachedMinBackoff {
+				m.backoff = memcachedMinBackoff
+			}
+			if m.backoff > memcachedMaxBackoff {
+				m.backoff = memcachedMaxBackoff
+			}
+			m.retryAt = time.Now().Add(m.backoff/2 + time.Duration(rand.Int63n(int64(m.backoff/2))))
+			m.dialErr = err
+		}
+		return nil, err
+	}
+	m.dialErr, m.backoff = nil, 0
+	return &memcachedConn{conn: conn, rw: bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))}, nil
+}
+
+// brokenConn reports whether err means the connection was closed by the server
+func brokenConn(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// release returns a connection to the idle connections or closes it
+func (m *MemcachedClient) release(c *memcachedConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed || len(m.idle) >= m.maxIdle {
+		c.conn.Close()
+		return
+	}
+	m.idle = append(m.idle, c)
+}
+
+// readMemcachedLine reads a response line without \r\n, error responses are returned as errors
+func readMemcachedLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+	if bytes.Equal(line, []byte("ERROR")) || bytes.HasPrefix(line, []byte("CLIENT_ERROR")) || bytes.HasPrefix(line, []byte("SERVER_ERROR")) {
+		return nil, fmt.Errorf("grc: memcached: %s", line)
+	}
+	return line, nil
+}
+
+// readMemcachedValues reads the values of a get response into values until END
+func readMemcachedValues(r *bufio.Reader, values map[string][]byte) error {
+	for {
+		line, err := readMemcachedLine(r)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(line, []byte("END")) {
+			return nil
+		}
+		// VALUE <key> <flags> <bytes>
+		fields := bytes.Fields(line)
+		if len(fields) < 4 || !bytes.Equal(fields[0], []byte("VALUE")) {
+			return fmt.Errorf("grc: unexpected memcached response: %q", line)
+		}
+		size, err := strconv.Atoi(string(fields[3]))
+		if err != nil {
+			return fmt.Errorf("grc: unexpected memcached response: %q", line)
+		}
+		value := make([]byte, size+2) // data and \r\n
+		if _, err := io.ReadFull(r, value); err != nil {
+			return err
+		}
+		values[string(fields[1])] = value[:size]
+	}
+}
+
+// expectMemcachedLine reads a response line and returns an error if it is not expected
+func expectMemcachedLine(r *bufio.Reader, expected string) error {
+	line, err := readMemcachedLine(r)
+	if err != nil {
+		return err
+	}
+	if string(line) != expected {
+		return fmt.Errorf("grc: unexpected memcached response: %q", line)
+	}
+	return nil
+}
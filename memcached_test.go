@@ -0,0 +1,287 @@
+package grc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMemcached is a memcached server supporting multi-key get, set and delete of the text protocol, without expiration
+type fakeMemcached struct {
+	listener net.Listener
+	mu       sync.Mutex
+	items    map[string][]byte
+	exptimes map[string]int64
+	conns    int        // number of accepted connections
+	open     []net.Conn // accepted connections, closed by drop
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &fakeMemcached{listener: listener, items: map[string][]byte{}, exptimes: map[string]int64{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.open = append(s.open, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+// drop closes all accepted connections, as a restarted server would
+func (s *fakeMemcached) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.open {
+		conn.Close()
+	}
+	s.open = nil
+}
+
+func (s *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			fmt.Fprint(conn, "ERROR\r\n")
+			continue
+		}
+
+		s.mu.Lock()
+		switch {
+		case fields[0] == "get" && len(fields) >= 2:
+			for _, key := range fields[1:] {
+				if value, ok := s.items[key]; ok {
+					fmt.Fprintf(conn, "VALUE %s 0 %d\r\n%s\r\n", key, len(value), value)
+				}
+			}
+			fmt.Fprint(conn, "END\r\n")
+		case fields[0] == "set" && len(fields) == 5:
+			size, _ := strconv.Atoi(fields[4])
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				s.mu.Unlock()
+				return
+			}
+			s.items[fields[1]] = data[:size]
+			s.exptimes[fields[1]], _ = strconv.ParseInt(fields[3], 10, 64)
+			fmt.Fprint(conn, "STORED\r\n")
+		case fields[0] == "version":
+			fmt.Fprint(conn, "VERSION 1.6.0\r\n")
+		case fields[0] == "delete" && len(fields) == 2:
+			if _, ok := s.items[fields[1]]; ok {
+				delete(s.items, fields[1])
+				fmt.Fprint(conn, "DELETED\r\n")
+			} else {
+				fmt.Fprint(conn, "NOT_FOUND\r\n")
+			}
+		default:
+			fmt.Fprint(conn, "SERVER_ERROR unsupported\r\n")
+		}
+		s.mu.Unlock()
+	}
+}
+
+// TestMemcachedClient tests get, set and deletion of the memcached client
+func TestMemcachedClient(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeMemcached(t)
+	client := NewMemcachedClient(server.listener.Addr().String(), MemcachedOptions{})
+	defer client.Close()
+
+	assert.NoError(t, client.Ping(ctx))
+	_, err := client.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A\r\nEND"), time.Minute))
+	assert.NoError(t, client.Set(ctx, "b", TestUser{ID: 1, Name: "B"}, 0))
+	assert.NoError(t, client.Set(ctx, "c", []byte{}, 1500*time.Millisecond))
+	assert.Equal(t, int64(60), server.exptimes["a"])
+	assert.Equal(t, int64(0), server.exptimes["b"])
+	assert.Equal(t, int64(2), server.exptimes["c"])
+
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A\r\nEND"), value)
+
+	value, err = client.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"ID":1,"Name":"B"}`), value)
+
+	value, err = client.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	assert.NoError(t, client.Del(ctx, "a", "missing"))
+	_, err = client.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.ErrorIs(t, client.Set(ctx, "with space", []byte("A"), 0), ErrMemcachedKey)
+	_, err = client.Get(ctx, strings.Repeat("k", 251))
+	assert.ErrorIs(t, err, ErrMemcachedKey)
+
+	// connections are reused
+	client.mu.Lock()
+	assert.Len(t, client.idle, 1)
+	client.mu.Unlock()
+}
+
+// TestMemcachedLargeValue tests a value with protocol lines in its data, large enough to be read from several TCP segments
+func TestMemcachedLargeValue(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeMemcached(t)
+	client := NewMemcachedClient(server.listener.Addr().String(), MemcachedOptions{})
+	defer client.Close()
+
+	value := bytes.Repeat([]byte("\r\nEND\r\nVALUE a 0 1\r\n\x00"), 1<<16)
+	assert.NoError(t, client.Set(ctx, "a", value, time.Minute))
+	got, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, value, got)
+}
+
+// TestMemcachedExptime tests converting ttls to memcached exptimes
+func TestMemcachedExptime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	assert.Equal(t, int64(0), memcachedExptime(0, now))
+	assert.Equal(t, int64(0), memcachedExptime(-time.Second, now))
+	assert.Equal(t, int64(1), memcachedExptime(time.Millisecond, now))
+	assert.Equal(t, int64(30*24*3600), memcachedExptime(30*24*time.Hour, now))
+	assert.Equal(t, now.Unix()+31*24*3600, memcachedExptime(31*24*time.Hour, now))
+}
+
+// TestMemcachedPool tests limiting the connections in use
+func TestMemcachedPool(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeMemcached(t)
+
+	client := NewMemcachedClient(server.listener.Addr().String(), MemcachedOptions{MaxActiveConns: 1})
+	defer client.Close()
+	assert.NoError(t, client.acquire(ctx)) // the only connection is in use
+	_, err := client.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMemcachedPoolExhausted)
+	client.releaseSlot()
+	_, err = client.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	waiting := NewMemcachedClient(server.listener.Addr().String(), MemcachedOptions{MaxActiveConns: 1, Wait: true})
+	defer waiting.Close()
+	assert.NoError(t, waiting.acquire(ctx))
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = waiting.Get(timeout, "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	waiting.releaseSlot()
+
+	// concurrent operations wait for the connections
+	server.mu.Lock()
+	server.conns = 0
+	server.mu.Unlock()
+	waiting = NewMemcachedClient(server.listener.Addr().String(), MemcachedOptions{MaxActiveConns: 2, Wait: true})
+	defer waiting.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, waiting.Set(ctx, strconv.Itoa(i), []byte("A"), time.Minute))
+		}(i)
+	}
+	wg.Wait()
+	server.mu.Lock()
+	assert.LessOrEqual(t, server.conns, 2)
+	server.mu.Unlock()
+}
+
+// TestMemcachedReconnect tests retrying on a new connection after the server closed an idle connection and backing off dialing
+func TestMemcachedReconnect(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeMemcached(t)
+	client := NewMemcachedClient(server.listener.Addr().String(), MemcachedOptions{})
+	defer client.Close()
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	server.drop()
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	// nothing listens on a closed listener's address
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	listener.Close()
+	down := NewMemcachedClient(listener.Addr().String(), MemcachedOptions{})
+	defer down.Close()
+	_, err = down.Get(ctx, "a")
+	assert.Error(t, err)
+	assert.Equal(t, memcachedMinBackoff, down.backoff)
+	assert.True(t, down.retryAt.After(time.Now()))
+
+	_, err2 := down.Get(ctx, "a") // fails fast within the backoff
+	assert.Same(t, err, err2)
+	down.retryAt = time.Now()
+	_, err = down.Get(ctx, "a")
+	assert.Error(t, err)
+	assert.Equal(t, 2*memcachedMinBackoff, down.backoff)
+}
+
+// TestMemcachedMulti tests pipelined batch get, set and deletion of the memcached client
+func TestMemcachedMulti(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeMemcached(t)
+	client := NewMemcachedClient(server.listener.Addr().String(), MemcachedOptions{})
+	defer client.Close()
+
+	values := make(map[string]interface{})
+	keys := make([]string, 0, 250)
+	for i := 0; i < 250; i++ {
+		key := strconv.Itoa(i)
+		values[key] = []byte("v" + key)
+		keys = append(keys, key)
+	}
+	assert.NoError(t, client.SetMulti(ctx, values, time.Minute))
+
+	got, err := client.GetMulti(ctx, append(keys, "missing")...)
+	assert.NoError(t, err)
+	assert.Len(t, got, 251)
+	assert.Equal(t, []byte("v0"), got[0])
+	assert.Equal(t, []byte("v249"), got[249])
+	assert.Nil(t, got[250])
+
+	assert.NoError(t, client.Del(ctx, keys...))
+	got, err = client.GetMulti(ctx, "0", "249")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, nil}, got)
+
+	_, err = client.GetMulti(ctx, "a", "with space")
+	assert.ErrorIs(t, err, ErrMemcachedKey)
+
+	// all operations used one connection
+	server.mu.Lock()
+	assert.Equal(t, 1, server.conns)
+	server.mu.Unlock()
+}
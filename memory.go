@@ -0,0 +1,618 @@
+package grc
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process cache client
+type MemoryCache struct {
+	shards    []*memoryShard
+	mask      uint32
+	now       func() time.Time
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// MemoryCacheOptions is a struct for memory cache options
+type MemoryCacheOptions struct {
+	MaxEntries int   // maximum number of entries, the least recently used entries are evicted, 0 means no limit
+	MaxBytes   int64 // maximum total size of keys and values, the least recently used entries are evicted, 0 means no limit
+	Shards     int   // number of independently locked shards, rounded up to a power of two, 0 means 1, limits are split evenly between shards
+
+	// CleanupInterval is the interval of deleting expired entries in the background, 0 means a minute,
+	// a negative value disables background cleanup, expired entries are then only deleted when replaced or evicted
+	CleanupInterval time.Duration
+
+	// Now returns the current time for expiration, nil means time.Now, e.g. a fake clock to test expiration without sleeping
+	Now func() time.Time
+}
+
+// memoryShard is a part of a MemoryCache with its own lock and lru list
+type memoryShard struct {
+	mu          sync.Mutex
+	items       map[string]*memoryItem
+	lru         *list.List     // of *memoryItem, the front is the most recently used
+	expirations expirationHeap // items with expiration, the earliest first
+	bytes       int64          // total size of items
+	maxEntries  int
+	maxBytes    int64
+}
+
+type memoryItem struct {
+	key      string
+	value    []byte
+	expireAt time.Time // zero means no expiration
+	element  *list.Element
+	index    int // index in the expiration heap, -1 if the item does not expire
+	hits     int64
+	accessAt time.Time // zero means never read
+}
+
+// MemoryEntry is a struct for statistics of a memory cache entry
+type MemoryEntry struct {
+	Key        string
+	Size       int64     // size of key and value
+	Hits       int64     // number of reads since the entry was set
+	LastAccess time.Time // time of the last read, zero means never read
+	ExpireAt   time.Time // zero means no expiration
+}
+
+// expirationHeap is a min-heap of items by expiration time, implementing heap.Interface
+type expirationHeap []*memoryItem
+
+func (h expirationHeap) Len() int {
+	return len(h)
+}
+
+func (h expirationHeap) Less(i, j int) bool {
+	return h[i].expireAt.Before(h[j].expireAt)
+}
+
+func (h expirationHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expirationHeap) Push(x interface{}) {
+	item := x.(*memoryItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expirationHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// size returns the accounted size of the item
+func (i *memoryItem) size() int64 {
+	return int64(len(i.key) + len(i.value))
+}
+
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expireAt.IsZero() && now.After(i.expireAt)
+}
+
+// ttl returns the remaining ttl of the item, 0 means no expiration
+func (i *memoryItem) ttl(now time.Time) time.Duration {
+	if i.expireAt.IsZero() {
+		return 0
+	}
+	return i.expireAt.Sub(now)
+}
+
+// NewMemoryCache returns a new MemoryCache instance without size limit, expired entries are cleaned up every minute until Close
+func NewMemoryCache() *MemoryCache {
+	return NewMemoryCacheWithOptions(MemoryCacheOptions{})
+}
+
+// NewMemoryCacheWithOptions returns a new MemoryCache instance with options, expired entries are cleaned up every CleanupInterval until Close
+func NewMemoryCacheWithOptions(options MemoryCacheOptions) *MemoryCache {
+	n := 1
+	for n < options.Shards {
+		n <<= 1
+	}
+
+	m := &MemoryCache{
+		shards: make([]*memoryShard, n),
+		mask:   uint32(n - 1),
+		now:    options.Now,
+		stop:   make(chan struct{}),
+	}
+	if m.now == nil {
+		m.now = time.Now
+	}
+	for i := range m.shards {
+		m.shards[i] = &memoryShard{
+			items:      make(map[string]*memoryItem),
+			lru:        list.New(),
+			maxEntries: splitLimit(options.MaxEntries, n),
+			maxBytes:   int64(splitLimit(int(options.MaxBytes), n)),
+		}
+	}
+	interval := options.CleanupInterval
+	if interval == 0 {
+		interval = time.Minute
+	}
+	if interval > 0 {
+		go m.cleanup(interval)
+	}
+	return m
+}
+
+// splitLimit splits limit evenly between n shards, rounding up so no shard has a zero limit
+func splitLimit(limit, n int) int {
+	if limit <= 0 {
+		return 0
+	}
+	return (limit + n - 1) / n
+}
+
+// shard returns the shard of key by its fnv-1a hash
+func (m *MemoryCache) shard(key string) *memoryShard {
+	if m.mask == 0 {
+		return m.shards[0]
+	}
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return m.shards[h&m.mask]
+}
+
+// Len returns the number of entries, including expired entries not cleaned up yet
+func (m *MemoryCache) Len() int {
+	n := 0
+	for _, s := range m.shards {
+		s.mu.Lock()
+		n += len(s.items)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Entries returns statistics of the unexpired entries, ordered by hits descending
+func (m *MemoryCache) Entries() []MemoryEntry {
+	now := m.now()
+	var entries []MemoryEntry
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for _, item := range s.items {
+			if item.expired(now) {
+				continue
+			}
+			entries = append(entries, MemoryEntry{
+				Key:        item.key,
+				Size:       item.size(),
+				Hits:       item.hits,
+				LastAccess: item.accessAt,
+				ExpireAt:   item.expireAt,
+			})
+		}
+		s.mu.Unlock()
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Hits != entries[j].Hits {
+			return entries[i].Hits > entries[j].Hits
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// Get gets value from memory by key, returns ErrCacheMiss if the key does not exist or is expired
+func (m *MemoryCache) Get(ctx context.Context, key string) (interface{}, error) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.lookup(key, m.now())
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return item.value, nil
+}
+
+// GetAndExpire gets value from memory by key and refreshes its ttl, ttl <= 0 means no expiration
+func (m *MemoryCache) GetAndExpire(ctx context.Context, key string, ttl time.Duration) (interface{}, error) {
+	now := m.now()
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.lookup(key, now)
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	s.expire(item, expireAt(now, ttl))
+	return item.value, nil
+}
+
+// GetWithTTL gets value from memory by key and its remaining ttl
+func (m *MemoryCache) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	now := m.now()
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.lookup(key, now)
+	if !ok {
+		return nil, 0, ErrCacheMiss
+	}
+	return item.value, item.ttl(now), nil
+}
+
+// Touch sets the ttl of a key in memory, ttl <= 0 removes the expiration, it does not count as a hit
+func (m *MemoryCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	now := m.now()
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.find(key, now)
+	if !ok {
+		return ErrCacheMiss
+	}
+	s.expire(item, expireAt(now, ttl))
+	return nil
+}
+
+// TTL gets the remaining ttl of a key in memory, it does not count as a hit
+func (m *MemoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	now := m.now()
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.find(key, now)
+	if !ok {
+		return 0, ErrCacheMiss
+	}
+	return item.ttl(now), nil
+}
+
+// GetMulti gets values from memory by keys, the values are in the order of keys and nil for missing or expired keys
+func (m *MemoryCache) GetMulti(ctx context.Context, keys ...string) ([]interface{}, error) {
+	now := m.now()
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		s := m.shard(key)
+		s.mu.Lock()
+		if item, ok := s.lookup(key, now); ok {
+			values[i] = item.value
+		}
+		s.mu.Unlock()
+	}
+	return values, nil
+}
+
+// GetMultiWithTTL gets values from memory by keys with their remaining ttls, the values are in the order of keys
+// and nil for missing or expired keys
+func (m *MemoryCache) GetMultiWithTTL(ctx context.Context, keys ...string) ([]interface{}, []time.Duration, error) {
+	now := m.now()
+	values := make([]interface{}, len(keys))
+	ttls := make([]time.Duration, len(keys))
+	for i, key := range keys {
+		s := m.shard(key)
+		s.mu.Lock()
+		if item, ok := s.lookup(key, now); ok {
+			values[i], ttls[i] = item.value, item.ttl(now)
+		}
+		s.mu.Unlock()
+	}
+	return values, ttls, nil
+}
+
+// Set sets value to memory by key with ttl using json encoding, []byte values are set as is, ttl <= 0 means no expiration
+func (m *MemoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	item, err := m.newMemoryItem(key, value, ttl)
+	if err != nil {
+		return err
+	}
+
+	s := m.shard(key)
+	s.mu.Lock()
+	s.add(item)
+	s.mu.Unlock()
+	return nil
+}
+
+// SetMulti sets values to memory by keys with ttl like Set
+func (m *MemoryCache) SetMulti(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	items := make([]*memoryItem, 0, len(values))
+	for key, value := range values {
+		item, err := m.newMemoryItem(key, value, ttl)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+
+	for _, item := range items {
+		s := m.shard(item.key)
+		s.mu.Lock()
+		s.add(item)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+func (m *MemoryCache) newMemoryItem(key string, value interface{}, ttl time.Duration) (*memoryItem, error) {
+	data, ok := value.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return &memoryItem{key: key, value: data, expireAt: expireAt(m.now(), ttl), index: -1}, nil
+}
+
+// expireAt returns the expiration time of ttl from now, ttl <= 0 means no expiration
+func expireAt(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// Del deletes keys from memory
+func (m *MemoryCache) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		s := m.shard(key)
+		s.mu.Lock()
+		if item, ok := s.items[key]; ok {
+			s.remove(item)
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Delete deletes key from memory and reports whether it existed
+func (m *MemoryCache) Delete(key string) bool {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if ok {
+		s.remove(item)
+	}
+	return ok
+}
+
+// FlushPrefix deletes all keys under prefix from memory, an empty prefix deletes all keys
+func (m *MemoryCache) FlushPrefix(ctx context.Context, prefix string) error {
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for key, item := range s.items {
+			if strings.HasPrefix(key, prefix) {
+				s.remove(item)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Flush deletes all entries from memory
+func (m *MemoryCache) Flush() {
+	for _, s := range m.shards {
+		s.mu.Lock()
+		s.items = make(map[string]*memoryItem)
+		s.lru.Init()
+		s.expirations = nil
+		s.bytes = 0
+		s.mu.Unlock()
+	}
+}
+
+// Keys returns the sorted keys of unexpired entries starting with prefix, an empty prefix matches all keys
+func (m *MemoryCache) Keys(prefix string) []string {
+	now := m.now()
+	var keys []string
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for key, item := range s.items {
+			if strings.HasPrefix(key, prefix) && !item.expired(now) {
+				keys = append(keys, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ListKeys lists at most limit sorted keys of unexpired entries under prefix, limit <= 0 means all keys
+func (m *MemoryCache) ListKeys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	keys := m.Keys(prefix)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+// memorySnapshotEntry is an entry of a memory cache snapshot
+type memorySnapshotEntry struct {
+	Key      string
+	Value    []byte
+	ExpireAt time.Time
+}
+
+// SaveTo writes a snapshot of the unexpired entries to w, which can be restored with LoadFrom,
+// e.g. to warm up the cache after a restart
+func (m *MemoryCache) SaveTo(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+	for _, s := range m.shards {
+		now := m.now()
+		s.mu.Lock()
+		entries := make([]memorySnapshotEntry, 0, len(s.items))
+		for e := s.lru.Back(); e != nil; e = e.Prev() { // least recently used first, so loading restores the order
+			item := e.Value.(*memoryItem)
+			if !item.expired(now) {
+				entries = append(entries, memorySnapshotEntry{Key: item.key, Value: item.value, ExpireAt: item.expireAt})
+			}
+		}
+		s.mu.Unlock()
+
+		for i := range entries {
+			if err := enc.Encode(&entries[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFrom reads a snapshot written by SaveTo from r and adds its unexpired entries,
+// replacing existing entries of the same keys and evicting entries over the limits
+func (m *MemoryCache) LoadFrom(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	for {
+		var entry memorySnapshotEntry
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		item := &memoryItem{key: entry.Key, value: entry.Value, expireAt: entry.ExpireAt, index: -1}
+		if item.expired(m.now()) {
+			continue
+		}
+		s := m.shard(item.key)
+		s.mu.Lock()
+		s.add(item)
+		s.mu.Unlock()
+	}
+}
+
+// Close stops the background cleanup, if any
+func (m *MemoryCache) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
+	return nil
+}
+
+// lookup returns the unexpired item of key, counts a hit and marks it as most recently used, s.mu must be held
+func (s *memoryShard) lookup(key string, now time.Time) (*memoryItem, bool) {
+	item, ok := s.find(key, now)
+	if !ok {
+		return nil, false
+	}
+	item.hits++
+	item.accessAt = now
+	s.lru.MoveToFront(item.element)
+	return item, true
+}
+
+// find returns the unexpired item of key, s.mu must be held
+func (s *memoryShard) find(key string, now time.Time) (*memoryItem, bool) {
+	item, ok := s.items[key]
+	if !ok || item.expired(now) {
+		return nil, false
+	}
+	return item, true
+}
+
+// add adds or replaces an item and evicts the least recently used items over MaxEntries or MaxBytes,
+// an item larger than MaxBytes is not added, s.mu must be held
+func (s *memoryShard) add(item *memoryItem) {
+	if old, ok := s.items[item.key]; ok {
+		s.remove(old)
+	}
+	if s.maxBytes > 0 && item.size() > s.maxBytes {
+		return
+	}
+	item.element = s.lru.PushFront(item)
+	s.items[item.key] = item
+	s.bytes += item.size()
+	if !item.expireAt.IsZero() {
+		heap.Push(&s.expirations, item)
+	}
+
+	for s.overLimit() {
+		s.remove(s.lru.Back().Value.(*memoryItem))
+	}
+}
+
+// overLimit reports whether the items exceed MaxEntries or MaxBytes, s.mu must be held
+func (s *memoryShard) overLimit() bool {
+	return s.maxEntries > 0 && len(s.items) > s.maxEntries || s.maxBytes > 0 && s.bytes > s.maxBytes
+}
+
+// remove removes an item, s.mu must be held
+func (s *memoryShard) remove(item *memoryItem) {
+	s.lru.Remove(item.element)
+	delete(s.items, item.key)
+	s.bytes -= item.size()
+	if item.index >= 0 {
+		heap.Remove(&s.expirations, item.index)
+	}
+}
+
+// expire sets the expiration time of an item and keeps the expiration heap in order, s.mu must be held
+func (s *memoryShard) expire(item *memoryItem, expireAt time.Time) {
+	item.expireAt = expireAt
+	switch {
+	case item.index >= 0 && expireAt.IsZero():
+		heap.Remove(&s.expirations, item.index)
+	case item.index >= 0:
+		heap.Fix(&s.expirations, item.index)
+	case !expireAt.IsZero():
+		heap.Push(&s.expirations, item)
+	}
+}
+
+// removeExpired removes expired items, proportional to their number, s.mu must be held
+func (s *memoryShard) removeExpired(now time.Time) {
+	for len(s.expirations) > 0 && s.expirations[0].expired(now) {
+		s.remove(s.expirations[0])
+	}
+}
+
+func (m *MemoryCache) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.cleanupExpired()
+		}
+	}
+}
+
+// cleanupExpired deletes all expired entries, locking one shard at a time
+func (m *MemoryCache) cleanupExpired() {
+	now := m.now()
+	for _, s := range m.shards {
+		s.mu.Lock()
+		s.removeExpired(now)
+		s.mu.Unlock()
+	}
+}
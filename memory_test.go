@@ -0,0 +1,425 @@
+package grc
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock for memory cache tests which only moves forward on Advance
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// TestMemoryCache tests get, set, expiration and deletion of the memory cache
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{Now: clock.Now})
+	defer cache.Close()
+
+	_, err := cache.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, cache.Set(ctx, "b", TestUser{ID: 1, Name: "B"}, 0))
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C"), time.Millisecond))
+
+	value, err := cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	value, err = cache.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"ID":1,"Name":"B"}`), value)
+
+	clock.Advance(time.Second)
+	_, err = cache.Get(ctx, "c")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	cache.cleanupExpired()
+	assert.Equal(t, 2, cache.Len())
+
+	assert.NoError(t, cache.Del(ctx, "a", "b"))
+	assert.Equal(t, 0, cache.Len())
+}
+
+// TestMemoryCacheGetAndExpire tests refreshing the ttl of entries on get
+func TestMemoryCacheGetAndExpire(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+	defer cache.Close()
+
+	_, err := cache.GetAndExpire(ctx, "missing", time.Minute)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Millisecond))
+	value, err := cache.GetAndExpire(ctx, "a", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	time.Sleep(5 * time.Millisecond)
+	value, err = cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+
+	_, err = cache.GetAndExpire(ctx, "a", 0)
+	assert.NoError(t, err)
+	assert.True(t, cache.shard("a").items["a"].expireAt.IsZero())
+}
+
+// TestMemoryCacheExt tests ttl introspection and touch of the memory cache
+func TestMemoryCacheExt(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+	defer cache.Close()
+	var client CacheClientExt = cache
+
+	assert.NoError(t, client.Set(ctx, "a", []byte("A"), time.Minute))
+	value, ttl, err := client.GetWithTTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	assert.InDelta(t, float64(time.Minute), float64(ttl), float64(time.Second))
+
+	assert.NoError(t, client.Touch(ctx, "a", time.Hour))
+	ttl, err = client.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+
+	assert.NoError(t, client.Touch(ctx, "a", 0))
+	ttl, err = client.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	assert.ErrorIs(t, client.Touch(ctx, "missing", time.Minute), ErrCacheMiss)
+	_, err = client.TTL(ctx, "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+// TestMemoryCacheLRU tests evicting the least recently used entries over MaxEntries
+func TestMemoryCacheLRU(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{MaxEntries: 2})
+	defer cache.Close()
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("B"), time.Minute))
+	_, err := cache.Get(ctx, "a") // b is the least recently used now
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C"), time.Minute))
+
+	_, err = cache.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	values, err := cache.GetMulti(ctx, "a", "c")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte("A"), []byte("C")}, values)
+
+	// replacing an entry does not evict others
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A2"), time.Minute))
+	assert.Equal(t, 2, cache.Len())
+	assert.Equal(t, 2, cache.shards[0].lru.Len())
+}
+
+// TestMemoryCacheMaxBytes tests evicting the least recently used entries over MaxBytes
+func TestMemoryCacheMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{MaxBytes: 20})
+	defer cache.Close()
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("123456789"), time.Minute)) // 10 bytes
+	assert.NoError(t, cache.Set(ctx, "b", []byte("123456789"), time.Minute))
+	assert.Equal(t, int64(20), cache.shards[0].bytes)
+
+	assert.NoError(t, cache.Set(ctx, "c", []byte("1234"), time.Minute))
+	_, err := cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, int64(15), cache.shards[0].bytes)
+
+	// too large for the budget, replaces and drops the old value
+	assert.NoError(t, cache.Set(ctx, "b", make([]byte, 20), time.Minute))
+	_, err = cache.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, int64(5), cache.shards[0].bytes)
+
+	assert.NoError(t, cache.Del(ctx, "c"))
+	assert.Equal(t, int64(0), cache.shards[0].bytes)
+}
+
+// TestMemoryCacheShards tests splitting entries and limits between shards
+func TestMemoryCacheShards(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{MaxEntries: 8, Shards: 3})
+	defer cache.Close()
+	assert.Len(t, cache.shards, 4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := strconv.Itoa(i*100 + j)
+				assert.NoError(t, cache.Set(ctx, key, []byte(key), time.Minute))
+				_, _ = cache.Get(ctx, key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, cache.Len(), 8)
+	for _, s := range cache.shards {
+		assert.LessOrEqual(t, len(s.items), 2)
+	}
+	assert.Equal(t, 1, splitLimit(1, 4))
+	assert.Equal(t, 0, splitLimit(0, 4))
+}
+
+// BenchmarkMemoryCache benchmarks concurrent gets and sets with and without shards
+func BenchmarkMemoryCache(b *testing.B) {
+	for _, shards := range []int{1, 16} {
+		b.Run("shards="+strconv.Itoa(shards), func(b *testing.B) {
+			ctx := context.Background()
+			cache := NewMemoryCacheWithOptions(MemoryCacheOptions{MaxEntries: 1000, Shards: shards})
+			defer cache.Close()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					key := strconv.Itoa(i % 2000)
+					if _, err := cache.Get(ctx, key); err != nil {
+						_ = cache.Set(ctx, key, []byte(key), time.Minute)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+// TestMemoryCacheExpirationHeap tests keeping expirations in order through sets, touches and deletes
+func TestMemoryCacheExpirationHeap(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{Now: clock.Now})
+	defer cache.Close()
+	s := cache.shards[0]
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Hour))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("B"), time.Millisecond))
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C"), time.Millisecond))
+	assert.NoError(t, cache.Set(ctx, "d", []byte("D"), 0))
+	assert.Len(t, s.expirations, 3)
+	assert.Equal(t, -1, s.items["d"].index)
+
+	assert.NoError(t, cache.Touch(ctx, "c", time.Hour))
+	assert.NoError(t, cache.Touch(ctx, "a", 0))
+	assert.Len(t, s.expirations, 2)
+	assert.Equal(t, "b", s.expirations[0].key)
+
+	clock.Advance(time.Second)
+	cache.cleanupExpired()
+	assert.Equal(t, 3, cache.Len())
+	assert.Len(t, s.expirations, 1)
+
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C2"), time.Minute)) // replaces the heap entry
+	assert.NoError(t, cache.Del(ctx, "a", "d"))
+	assert.Len(t, s.expirations, 1)
+	assert.Equal(t, 0, s.items["c"].index)
+}
+
+// TestMemoryCacheEntries tests per-entry statistics
+func TestMemoryCacheEntries(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{Shards: 4})
+	defer cache.Close()
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Minute))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("BB"), 0))
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C"), time.Millisecond))
+	for i := 0; i < 3; i++ {
+		_, err := cache.Get(ctx, "b")
+		assert.NoError(t, err)
+	}
+	_, err := cache.GetMulti(ctx, "a", "b")
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Touch(ctx, "a", time.Hour)) // not a hit
+	_, err = cache.TTL(ctx, "a")
+	assert.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	entries := cache.Entries()
+	assert.Len(t, entries, 2) // c is expired
+	assert.Equal(t, "b", entries[0].Key)
+	assert.Equal(t, int64(4), entries[0].Hits)
+	assert.Equal(t, int64(3), entries[0].Size)
+	assert.True(t, entries[0].ExpireAt.IsZero())
+	assert.Equal(t, "a", entries[1].Key)
+	assert.Equal(t, int64(1), entries[1].Hits)
+	assert.WithinDuration(t, time.Now(), entries[1].LastAccess, time.Second)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), entries[1].ExpireAt, time.Second)
+
+	assert.NoError(t, cache.Set(ctx, "b", []byte("B"), 0)) // resets statistics
+	assert.Equal(t, int64(0), cache.Entries()[1].Hits)
+}
+
+// TestMemoryCacheDeleteFlushKeys tests purging entries
+func TestMemoryCacheDeleteFlushKeys(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{Shards: 4})
+	defer cache.Close()
+
+	for _, key := range []string{"users:2", "users:1", "orders:1"} {
+		assert.NoError(t, cache.Set(ctx, key, []byte(key), time.Minute))
+	}
+	assert.NoError(t, cache.Set(ctx, "users:3", []byte("expired"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	assert.Equal(t, []string{"users:1", "users:2"}, cache.Keys("users:"))
+	assert.Equal(t, []string{"orders:1", "users:1", "users:2"}, cache.Keys(""))
+	assert.Empty(t, cache.Keys("products:"))
+
+	assert.True(t, cache.Delete("users:1"))
+	assert.False(t, cache.Delete("users:1"))
+	assert.Equal(t, []string{"users:2"}, cache.Keys("users:"))
+
+	cache.Flush()
+	assert.Equal(t, 0, cache.Len())
+	assert.Empty(t, cache.Keys(""))
+	for _, s := range cache.shards {
+		assert.Equal(t, int64(0), s.bytes)
+		assert.Empty(t, s.expirations)
+	}
+
+	assert.NoError(t, cache.Set(ctx, "users:1", []byte("A"), time.Minute)) // usable after Flush
+	assert.Equal(t, []string{"users:1"}, cache.Keys(""))
+}
+
+// TestMemoryCacheFlushPrefix tests deleting all keys under a prefix
+func TestMemoryCacheFlushPrefix(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{Shards: 4})
+	defer cache.Close()
+
+	for _, key := range []string{"users:1", "users:2", "orders:1"} {
+		assert.NoError(t, cache.Set(ctx, key, []byte("A"), time.Minute))
+	}
+	assert.NoError(t, cache.FlushPrefix(ctx, "users:"))
+	assert.Equal(t, []string{"orders:1"}, cache.Keys(""))
+	assert.Equal(t, 1, cache.Len())
+
+	var bytes int64
+	for _, s := range cache.shards {
+		bytes += s.bytes
+	}
+	assert.Equal(t, int64(len("orders:1")+1), bytes)
+
+	assert.NoError(t, cache.FlushPrefix(ctx, ""))
+	assert.Equal(t, 0, cache.Len())
+}
+
+// TestMemoryCacheSnapshot tests saving and restoring entries
+func TestMemoryCacheSnapshot(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+	defer cache.Close()
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Hour))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("B"), 0))
+	assert.NoError(t, cache.Set(ctx, "c", []byte("C"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.SaveTo(&buf))
+
+	restored := NewMemoryCacheWithOptions(MemoryCacheOptions{Shards: 4})
+	defer restored.Close()
+	assert.NoError(t, restored.LoadFrom(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, []string{"a", "b"}, restored.Keys(""))
+
+	value, ttl, err := restored.GetWithTTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second))
+	ttl, err = restored.TTL(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	// the order of use is restored, so limits evict the least recently used entries
+	lru := NewMemoryCacheWithOptions(MemoryCacheOptions{MaxEntries: 1})
+	defer lru.Close()
+	_, err = cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, cache.SaveTo(&buf))
+	assert.NoError(t, lru.LoadFrom(&buf))
+	assert.Equal(t, []string{"a"}, lru.Keys(""))
+
+	assert.Error(t, restored.LoadFrom(bytes.NewReader([]byte("invalid"))))
+}
+
+// TestMemoryCacheClock tests expiration with an injected clock
+func TestMemoryCacheClock(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{Now: clock.Now})
+	defer cache.Close()
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Hour))
+	clock.Advance(59 * time.Minute)
+	ttl, err := cache.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	_, err = cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, clock.Now(), cache.Entries()[0].LastAccess)
+
+	clock.Advance(time.Minute + time.Nanosecond)
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	cache.cleanupExpired()
+	assert.Equal(t, 0, cache.Len())
+}
+
+// TestMemoryCacheCleanupInterval tests configuring and disabling the background cleanup
+func TestMemoryCacheCleanupInterval(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	cache := NewMemoryCacheWithOptions(MemoryCacheOptions{CleanupInterval: 5 * time.Millisecond, Now: clock.Now})
+	defer cache.Close()
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Second))
+	clock.Advance(2 * time.Second)
+	assert.Eventually(t, func() bool {
+		return cache.Len() == 0
+	}, time.Second, 5*time.Millisecond)
+
+	disabled := NewMemoryCacheWithOptions(MemoryCacheOptions{CleanupInterval: -1, Now: clock.Now})
+	assert.NoError(t, disabled.Set(ctx, "a", []byte("A"), time.Second))
+	clock.Advance(2 * time.Second)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, disabled.Len())
+	assert.NoError(t, disabled.Close())
+}
@@ -0,0 +1,445 @@
+package grc
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Middleware wraps a CacheClient to add cross-cutting behavior
+type Middleware func(client CacheClient) CacheClient
+
+// Chain wraps client with middlewares, the first middleware is the outermost one.
+// The optional interfaces of client, e.g. CacheClientExt, KeyLister or Pinger, are forwarded through the middlewares.
+// The middleware clients implement all of them and return ErrNotSupported for those client does not implement,
+// except GetMulti and SetMulti, which fall back to one Get or Set per key.
+func Chain(client CacheClient, middlewares ...Middleware) CacheClient {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		client = middlewares[i](client)
+	}
+	return client
+}
+
+// clientFuncs is a CacheClient built from functions, used by middlewares,
+// the functions of optional interfaces are nil if the wrapped client does not implement them
+type clientFuncs struct {
+	get         func(ctx context.Context, key string) (interface{}, error)
+	set         func(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	del         func(ctx context.Context, keys ...string) error
+	flushPrefix func(ctx context.Context, prefix string) error
+
+	getMulti        func(ctx context.Context, keys ...string) ([]interface{}, error)
+	getMultiWithTTL func(ctx context.Context, keys ...string) ([]interface{}, []time.Duration, error)
+	setMulti        func(ctx context.Context, values map[string]interface{}, ttl time.Duration) error
+	getWithTTL      func(ctx context.Context, key string) (interface{}, time.Duration, error)
+	touch           func(ctx context.Context, key string, ttl time.Duration) error
+	ttl             func(ctx context.Context, key string) (time.Duration, error)
+	getAndExpire    func(ctx context.Context, key string, ttl time.Duration) (interface{}, error)
+	getOrSet        func(ctx context.Context, key string, value interface{}, ttl time.Duration) (interface{}, bool, error)
+	listKeys        func(ctx context.Context, prefix string, limit int) ([]string, error)
+	ping            func(ctx context.Context) error
+}
+
+// passThrough returns clientFuncs forwarding all operations to client,
+// Del and FlushPrefix return ErrNotSupported if client does not implement them
+func passThrough(client CacheClient) clientFuncs {
+	c := clientFuncs{
+		get: client.Get,
+		set: client.Set,
+		del: func(ctx context.Context, keys ...string) error {
+			return ErrNotSupported
+		},
+		flushPrefix: func(ctx context.Context, prefix string) error {
+			return ErrNotSupported
+		},
+	}
+	if deleter, ok := client.(KeyDeleter); ok {
+		c.del = deleter.Del
+	}
+	if flusher, ok := client.(PrefixFlusher); ok {
+		c.flushPrefix = flusher.FlushPrefix
+	}
+	if getter, ok := client.(MultiGetter); ok {
+		c.getMulti = getter.GetMulti
+	}
+	if getter, ok := client.(MultiTTLGetter); ok {
+		c.getMultiWithTTL = getter.GetMultiWithTTL
+	}
+	if setter, ok := client.(MultiSetter); ok {
+		c.setMulti = setter.SetMulti
+	}
+	if ext, ok := client.(CacheClientExt); ok {
+		c.getWithTTL, c.touch, c.ttl = ext.GetWithTTL, ext.Touch, ext.TTL
+	}
+	if getter, ok := client.(ExpiringGetter); ok {
+		c.getAndExpire = getter.GetAndExpire
+	}
+	if setter, ok := client.(GetOrSetter); ok {
+		c.getOrSet = setter.GetOrSet
+	}
+	if lister, ok := client.(KeyLister); ok {
+		c.listKeys = lister.ListKeys
+	}
+	if pinger, ok := client.(Pinger); ok {
+		c.ping = pinger.Ping
+	}
+	return c
+}
+
+// wrapOptional sets the functions of the optional interfaces forwarded from next, which are not
+// Get, Set, Del or FlushPrefix, to call them through call with the operation name, e.g. "get_multi"
+func (c *clientFuncs) wrapOptional(next clientFuncs, call func(ctx context.Context, op string, f func() error) error) {
+	if next.getMulti != nil {
+		c.getMulti = func(ctx context.Context, keys ...string) (values []interface{}, err error) {
+			err = call(ctx, "get_multi", func() error {
+				values, err = next.getMulti(ctx, keys...)
+				return err
+			})
+			return values, err
+		}
+	}
+	if next.getMultiWithTTL != nil {
+		c.getMultiWithTTL = func(ctx context.Context, keys ...string) (values []interface{}, ttls []time.Duration, err error) {
+			err = call(ctx, "get_multi_with_ttl", func() error {
+				values, ttls, err = next.getMultiWithTTL(ctx, keys...)
+				return err
+			})
+			return values, ttls, err
+		}
+	}
+	if next.setMulti != nil {
+		c.setMulti = func(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+			return call(ctx, "set_multi", func() error { return next.setMulti(ctx, values, ttl) })
+		}
+	}
+	if next.getWithTTL != nil {
+		c.getWithTTL = func(ctx context.Context, key string) (value interface{}, ttl time.Duration, err error) {
+			err = call(ctx, "get_with_ttl", func() error {
+				value, ttl, err = next.getWithTTL(ctx, key)
+				return err
+			})
+			return value, ttl, err
+		}
+	}
+	if next.touch != nil {
+		c.touch = func(ctx context.Context, key string, ttl time.Duration) error {
+			return call(ctx, "touch", func() error { return next.touch(ctx, key, ttl) })
+		}
+	}
+	if next.ttl != nil {
+		c.ttl = func(ctx context.Context, key string) (ttl time.Duration, err error) {
+			err = call(ctx, "ttl", func() error {
+				ttl, err = next.ttl(ctx, key)
+				return err
+			})
+			return ttl, err
+		}
+	}
+	if next.getAndExpire != nil {
+		c.getAndExpire = func(ctx context.Context, key string, ttl time.Duration) (value interface{}, err error) {
+			err = call(ctx, "get_and_expire", func() error {
+				value, err = next.getAndExpire(ctx, key, ttl)
+				return err
+			})
+			return value, err
+		}
+	}
+	if next.getOrSet != nil {
+		c.getOrSet = func(ctx context.Context, key string, value interface{}, ttl time.Duration) (actual interface{}, loaded bool, err error) {
+			err = call(ctx, "get_or_set", func() error {
+				actual, loaded, err = next.getOrSet(ctx, key, value, ttl)
+				return err
+			})
+			return actual, loaded, err
+		}
+	}
+	if next.listKeys != nil {
+		c.listKeys = func(ctx context.Context, prefix string, limit int) (keys []string, err error) {
+			err = call(ctx, "list_keys", func() error {
+				keys, err = next.listKeys(ctx, prefix, limit)
+				return err
+			})
+			return keys, err
+		}
+	}
+	if next.ping != nil {
+		c.ping = func(ctx context.Context) error {
+			return call(ctx, "ping", func() error { return next.ping(ctx) })
+		}
+	}
+}
+
+func (c clientFuncs) Get(ctx context.Context, key string) (interface{}, error) {
+	return c.get(ctx, key)
+}
+
+func (c clientFuncs) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.set(ctx, key, value, ttl)
+}
+
+func (c clientFuncs) Del(ctx context.Context, keys ...string) error {
+	return c.del(ctx, keys...)
+}
+
+func (c clientFuncs) FlushPrefix(ctx context.Context, prefix string) error {
+	return c.flushPrefix(ctx, prefix)
+}
+
+// GetMulti gets values by keys with GetMulti of the wrapped client, or one Get per key if it is not a MultiGetter
+func (c clientFuncs) GetMulti(ctx context.Context, keys ...string) ([]interface{}, error) {
+	if c.getMulti != nil {
+		return c.getMulti(ctx, keys...)
+	}
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, err := c.get(ctx, key)
+		if err != nil && !isCacheMiss(err) {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// SetMulti sets values with SetMulti of the wrapped client, or one Set per key if it is not a MultiSetter
+func (c clientFuncs) SetMulti(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if c.setMulti != nil {
+		return c.setMulti(ctx, values, ttl)
+	}
+	for key, value := range values {
+		if err := c.set(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c clientFuncs) GetMultiWithTTL(ctx context.Context, keys ...string) ([]interface{}, []time.Duration, error) {
+	if c.getMultiWithTTL == nil {
+		return nil, nil, ErrNotSupported
+	}
+	return c.getMultiWithTTL(ctx, keys...)
+}
+
+func (c clientFuncs) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	if c.getWithTTL == nil {
+		return nil, 0, ErrNotSupported
+	}
+	return c.getWithTTL(ctx, key)
+}
+
+func (c clientFuncs) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.touch == nil {
+		return ErrNotSupported
+	}
+	return c.touch(ctx, key, ttl)
+}
+
+func (c clientFuncs) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.ttl == nil {
+		return 0, ErrNotSupported
+	}
+	return c.ttl(ctx, key)
+}
+
+func (c clientFuncs) GetAndExpire(ctx context.Context, key string, ttl time.Duration) (interface{}, error) {
+	if c.getAndExpire == nil {
+		return nil, ErrNotSupported
+	}
+	return c.getAndExpire(ctx, key, ttl)
+}
+
+func (c clientFuncs) GetOrSet(ctx context.Context, key string, value interface{}, ttl time.Duration) (interface{}, bool, error) {
+	if c.getOrSet == nil {
+		return nil, false, ErrNotSupported
+	}
+	return c.getOrSet(ctx, key, value, ttl)
+}
+
+func (c clientFuncs) ListKeys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	if c.listKeys == nil {
+		return nil, ErrNotSupported
+	}
+	return c.listKeys(ctx, prefix, limit)
+}
+
+func (c clientFuncs) Ping(ctx context.Context) error {
+	if c.ping == nil {
+		return ErrNotSupported
+	}
+	return c.ping(ctx)
+}
+
+// WithMetrics returns a Middleware calling observe with the operation name, duration and error of every call
+func WithMetrics(observe func(op string, duration time.Duration, err error)) Middleware {
+	return func(client CacheClient) CacheClient {
+		next := passThrough(client)
+		c := next
+		c.get = func(ctx context.Context, key string) (interface{}, error) {
+			start := time.Now()
+			value, err := next.get(ctx, key)
+			observe("get", time.Since(start), err)
+			return value, err
+		}
+		c.set = func(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+			start := time.Now()
+			err := next.set(ctx, key, value, ttl)
+			observe("set", time.Since(start), err)
+			return err
+		}
+		c.del = func(ctx context.Context, keys ...string) error {
+			start := time.Now()
+			err := next.del(ctx, keys...)
+			observe("del", time.Since(start), err)
+			return err
+		}
+		c.wrapOptional(next, func(ctx context.Context, op string, f func() error) error {
+			start := time.Now()
+			err := f()
+			observe(op, time.Since(start), err)
+			return err
+		})
+		return c
+	}
+}
+
+// WithLogging returns a Middleware logging every call with its key, duration and error, logger defaults to log.Default()
+func WithLogging(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(client CacheClient) CacheClient {
+		next := passThrough(client)
+		c := next
+		c.get = func(ctx context.Context, key string) (interface{}, error) {
+			start := time.Now()
+			value, err := next.get(ctx, key)
+			logger.Printf("cache get, key: %v, duration: %v, err: %v", key, time.Since(start), err)
+			return value, err
+		}
+		c.set = func(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+			start := time.Now()
+			err := next.set(ctx, key, value, ttl)
+			logger.Printf("cache set, key: %v, ttl: %v, duration: %v, err: %v", key, ttl, time.Since(start), err)
+			return err
+		}
+		c.del = func(ctx context.Context, keys ...string) error {
+			start := time.Now()
+			err := next.del(ctx, keys...)
+			logger.Printf("cache del, keys: %v, duration: %v, err: %v", keys, time.Since(start), err)
+			return err
+		}
+		c.wrapOptional(next, func(ctx context.Context, op string, f func() error) error {
+			start := time.Now()
+			err := f()
+			logger.Printf("cache %s, duration: %v, err: %v", op, time.Since(start), err)
+			return err
+		})
+		return c
+	}
+}
+
+// WithStage returns a Middleware applying stage to []byte values on Set and reversing it on Get,
+// the values of the optional interfaces like GetMulti, GetWithTTL or GetOrSet are transformed as well.
+// A KeyedStage is called with the key of each value.
+func WithStage(stage Stage) Middleware {
+	decode := func(key string, value interface{}) (interface{}, error) {
+		if data, ok := value.([]byte); ok {
+			return decodeStage(stage, key, data)
+		}
+		return value, nil
+	}
+	encode := func(key string, value interface{}) (interface{}, error) {
+		if data, ok := value.([]byte); ok {
+			return encodeStage(stage, key, data)
+		}
+		return value, nil
+	}
+	return func(client CacheClient) CacheClient {
+		next := passThrough(client)
+		c := next
+		c.get = func(ctx context.Context, key string) (interface{}, error) {
+			value, err := next.get(ctx, key)
+			if err != nil {
+				return value, err
+			}
+			return decode(key, value)
+		}
+		c.set = func(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+			value, err := encode(key, value)
+			if err != nil {
+				return err
+			}
+			return next.set(ctx, key, value, ttl)
+		}
+		if next.getMulti != nil {
+			c.getMulti = func(ctx context.Context, keys ...string) ([]interface{}, error) {
+				values, err := next.getMulti(ctx, keys...)
+				for i := 0; err == nil && i < len(values); i++ {
+					values[i], err = decode(keys[i], values[i])
+				}
+				return values, err
+			}
+		}
+		if next.getMultiWithTTL != nil {
+			c.getMultiWithTTL = func(ctx context.Context, keys ...string) ([]interface{}, []time.Duration, error) {
+				values, ttls, err := next.getMultiWithTTL(ctx, keys...)
+				for i := 0; err == nil && i < len(values); i++ {
+					values[i], err = decode(keys[i], values[i])
+				}
+				return values, ttls, err
+			}
+		}
+		if next.setMulti != nil {
+			c.setMulti = func(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+				encoded := make(map[string]interface{}, len(values))
+				for key, value := range values {
+					var err error
+					if encoded[key], err = encode(key, value); err != nil {
+						return err
+					}
+				}
+				return next.setMulti(ctx, encoded, ttl)
+			}
+		}
+		if next.getWithTTL != nil {
+			c.getWithTTL = func(ctx context.Context, key string) (interface{}, time.Duration, error) {
+				value, ttl, err := next.getWithTTL(ctx, key)
+				if err != nil {
+					return value, ttl, err
+				}
+				value, err = decode(key, value)
+				return value, ttl, err
+			}
+		}
+		if next.getAndExpire != nil {
+			c.getAndExpire = func(ctx context.Context, key string, ttl time.Duration) (interface{}, error) {
+				value, err := next.getAndExpire(ctx, key, ttl)
+				if err != nil {
+					return value, err
+				}
+				return decode(key, value)
+			}
+		}
+		if next.getOrSet != nil {
+			c.getOrSet = func(ctx context.Context, key string, value interface{}, ttl time.Duration) (interface{}, bool, error) {
+				value, err := encode(key, value)
+				if err != nil {
+					return nil, false, err
+				}
+				actual, loaded, err := next.getOrSet(ctx, key, value, ttl)
+				if err != nil {
+					return actual, loaded, err
+				}
+				actual, err = decode(key, actual)
+				return actual, loaded, err
+			}
+		}
+		return c
+	}
+}
+
+// WithCompression returns a Middleware compressing []byte values with gzip at level
+func WithCompression(level int) Middleware {
+	return WithStage(NewGzipStage(level))
+}
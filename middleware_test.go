@@ -0,0 +1,143 @@
+package grc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapClient is a CacheClient backed by a map for tests
+type mapClient struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+func newMapClient() *mapClient {
+	return &mapClient{values: make(map[string]interface{})}
+}
+
+func (c *mapClient) Get(ctx context.Context, key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.values[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return value, nil
+}
+
+func (c *mapClient) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[key] = value
+	return nil
+}
+
+// TestChain tests wrapping a client with middlewares
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	inner := newMapClient()
+
+	var ops []string
+	var buf bytes.Buffer
+	client := Chain(inner,
+		WithMetrics(func(op string, duration time.Duration, err error) {
+			ops = append(ops, op)
+		}),
+		WithLogging(log.New(&buf, "", 0)),
+		WithCompression(gzip.BestSpeed),
+	)
+
+	value := []byte(`[{"ID":1,"Name":"A"}]`)
+	assert.NoError(t, client.Set(ctx, "key", value, time.Minute))
+	assert.NotEqual(t, value, inner.values["key"]) // compressed
+
+	got, err := client.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	assert.Equal(t, []string{"set", "get"}, ops)
+	assert.Contains(t, buf.String(), "cache set, key: key")
+	assert.Contains(t, buf.String(), "cache get, key: key")
+}
+
+// TestChainDel tests forwarding Del through middlewares
+func TestChainDel(t *testing.T) {
+	ctx := context.Background()
+
+	var ops []string
+	client := Chain(newMapClient(), WithMetrics(func(op string, duration time.Duration, err error) {
+		ops = append(ops, op)
+	}))
+	assert.ErrorIs(t, client.(KeyDeleter).Del(ctx, "key"), ErrNotSupported)
+	assert.Equal(t, []string{"del"}, ops)
+
+	client = Chain(NewRedisClient(rdb), WithLogging(nil))
+	assert.NoError(t, client.Set(ctx, "chain:a", []byte("A"), time.Minute))
+	assert.NoError(t, client.(KeyDeleter).Del(ctx, "chain:a"))
+	assert.Equal(t, int64(0), rdb.Exists(ctx, "chain:a").Val())
+}
+
+// TestChainOptional tests forwarding the optional interfaces of the client through middlewares
+func TestChainOptional(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryCache()
+
+	var ops []string
+	client := Chain(inner,
+		WithMetrics(func(op string, duration time.Duration, err error) {
+			ops = append(ops, op)
+		}),
+		WithCircuitBreaker(CircuitBreakerOptions{}),
+		WithRetry(RetryOptions{}),
+		WithCompression(gzip.BestSpeed),
+	)
+	value := []byte(`[{"ID":1}]`)
+	assert.NoError(t, client.Set(ctx, "chain:a", value, time.Minute))
+
+	got, ttl, err := client.(CacheClientExt).GetWithTTL(ctx, "chain:a")
+	assert.NoError(t, err)
+	assert.Equal(t, value, got) // decompressed
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+	assert.NoError(t, client.(CacheClientExt).Touch(ctx, "chain:a", time.Hour))
+	ttl, err = client.(CacheClientExt).TTL(ctx, "chain:a")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+
+	keys, err := client.(KeyLister).ListKeys(ctx, "chain:", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"chain:a"}, keys)
+
+	assert.NoError(t, client.(MultiSetter).SetMulti(ctx, map[string]interface{}{"chain:b": value}, time.Minute))
+	values, err := client.(MultiGetter).GetMulti(ctx, "chain:a", "chain:b", "chain:c")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{value, value, nil}, values)
+	values, ttls, err := client.(MultiTTLGetter).GetMultiWithTTL(ctx, "chain:b", "chain:c")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{value, nil}, values)
+	assert.InDelta(t, time.Minute, ttls[0], float64(time.Second))
+	assert.Equal(t, []string{"set", "get_with_ttl", "touch", "ttl", "list_keys", "set_multi", "get_multi", "get_multi_with_ttl"}, ops)
+
+	// the client implements neither, GetMulti falls back to Get
+	client = Chain(newMapClient(), WithCompression(gzip.BestSpeed))
+	_, _, err = client.(CacheClientExt).GetWithTTL(ctx, "chain:a")
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = client.(KeyLister).ListKeys(ctx, "chain:", 0)
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, _, err = client.(MultiTTLGetter).GetMultiWithTTL(ctx, "chain:a")
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.ErrorIs(t, client.(Pinger).Ping(ctx), ErrNotSupported)
+	assert.NoError(t, client.Set(ctx, "chain:a", value, 0))
+	values, err = client.(MultiGetter).GetMulti(ctx, "chain:a", "chain:b")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{value, nil}, values)
+	assert.ErrorIs(t, NewGormCache("chain_cache", client, CacheConfig{}).HealthCheck(ctx, time.Second), ErrNotSupported)
+}
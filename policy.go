@@ -0,0 +1,107 @@
+package grc
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// isReadOnlySQL reports whether a raw sql statement is a query
+func isReadOnlySQL(sql string) bool {
+	sql = strings.ToLower(strings.TrimLeft(sql, " \t\r\n("))
+	return strings.HasPrefix(sql, "select") || strings.HasPrefix(sql, "with")
+}
+
+// isRawQuery reports whether the sql of db is raw sql, which is set before callbacks,
+// unlike the sql of other queries, which is built from clauses starting with SELECT
+func isRawQuery(db *gorm.DB) bool {
+	if db.Statement.SQL.Len() == 0 {
+		return false
+	}
+	_, built := db.Statement.Clauses["SELECT"]
+	return !built
+}
+
+// lockingSQL matches locking clauses of postgres and mysql
+var lockingSQL = regexp.MustCompile(`(?i)\bfor\s+(update|share|no\s+key\s+update|key\s+share)\b|\block\s+in\s+share\s+mode\b`)
+
+// isLockingRead reports whether a query acquires row locks, its result must come from the database to acquire them
+func isLockingRead(db *gorm.DB) bool {
+	if _, ok := db.Statement.Clauses["FOR"]; ok {
+		return true
+	}
+	return lockingSQL.MatchString(db.Statement.SQL.String())
+}
+
+// DefaultVolatileFuncs are sql functions whose results differ between calls of the same statement
+var DefaultVolatileFuncs = []string{
+	"NOW", "CURRENT_TIMESTAMP", "CURRENT_DATE", "CURRENT_TIME", "LOCALTIMESTAMP", "LOCALTIME",
+	"CLOCK_TIMESTAMP", "STATEMENT_TIMESTAMP", "TRANSACTION_TIMESTAMP", "TIMEOFDAY",
+	"SYSDATE", "SYSDATETIME", "GETDATE", "GETUTCDATE", "CURDATE", "CURTIME", "UNIX_TIMESTAMP", "UTC_TIMESTAMP",
+	"RANDOM", "RAND", "UUID", "UUID_SHORT", "GEN_RANDOM_UUID", "UUID_GENERATE_V4", "NEWID", "NEXTVAL",
+}
+
+// niladicSQLFuncs are sql functions called without parentheses, which are matched as bare words
+var niladicSQLFuncs = map[string]bool{
+	"CURRENT_TIMESTAMP": true, "CURRENT_DATE": true, "CURRENT_TIME": true, "LOCALTIMESTAMP": true, "LOCALTIME": true,
+}
+
+// isVolatileSQL reports whether sql calls one of the volatile functions of config, ignoring case.
+// Functions must be followed by parentheses, except niladic ones like CURRENT_TIMESTAMP,
+// so columns named like functions, e.g. uuid or now, do not match. Quoted identifiers and string literals are skipped.
+func isVolatileSQL(sql string, config CacheConfig) bool {
+	funcs := config.VolatileFuncs
+	if funcs == nil {
+		funcs = DefaultVolatileFuncs
+	}
+	sql = strings.ToUpper(blankQuoted(sql))
+	for _, fn := range funcs {
+		fn = strings.ToUpper(fn)
+		if containsCall(sql, fn, niladicSQLFuncs[fn]) {
+			return true
+		}
+	}
+	return false
+}
+
+// blankQuoted replaces the contents of string literals and quoted identifiers in sql with spaces
+func blankQuoted(sql string) string {
+	b := []byte(sql)
+	var quote byte
+	for i, c := range b {
+		switch {
+		case quote == 0 && (c == '\'' || c == '"' || c == '`'):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0 // a doubled quote escapes it, blanking both halves keeps the literal closed correctly
+		case quote != 0:
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// containsCall reports whether word occurs in s not adjacent to other identifier characters,
+// followed by an opening parenthesis unless bare is set
+func containsCall(s, word string, bare bool) bool {
+	if word == "" {
+		return false
+	}
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		if (start == 0 || !isIdentByte(s[start-1])) && (end == len(s) || !isIdentByte(s[end])) &&
+			(bare || strings.HasPrefix(strings.TrimLeft(s[end:], " \t\r\n"), "(")) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
@@ -0,0 +1,59 @@
+package grc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TestIsReadOnlySQL tests detecting raw queries
+func TestIsReadOnlySQL(t *testing.T) {
+	assert.True(t, isReadOnlySQL("SELECT * FROM users"))
+	assert.True(t, isReadOnlySQL("  (select 1) union (select 2)"))
+	assert.True(t, isReadOnlySQL("WITH t AS (SELECT 1) SELECT * FROM t"))
+	assert.False(t, isReadOnlySQL("UPDATE users SET name = 'a' RETURNING *"))
+	assert.False(t, isReadOnlySQL("DELETE FROM users"))
+}
+
+// TestIsLockingRead tests detecting locking clauses
+func TestIsLockingRead(t *testing.T) {
+	dryDB := db.Session(&gorm.Session{DryRun: true})
+
+	stmt := dryDB.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&[]TestUser{}).Statement
+	assert.True(t, isLockingRead(stmt.DB))
+
+	stmt = dryDB.Raw("SELECT * FROM test_users WHERE id = ? for  no key update", 1).Statement
+	assert.True(t, isLockingRead(stmt.DB))
+
+	stmt = dryDB.Raw("SELECT * FROM test_users WHERE id = ? LOCK IN SHARE MODE", 1).Statement
+	assert.True(t, isLockingRead(stmt.DB))
+
+	stmt = dryDB.Raw("SELECT * FROM test_users WHERE name = 'for update'").Statement
+	assert.True(t, isLockingRead(stmt.DB)) // literals are not parsed, bypassing cache is the safe side
+
+	stmt = dryDB.Find(&[]TestUser{}).Statement
+	assert.False(t, isLockingRead(stmt.DB))
+}
+
+// TestIsVolatileSQL tests detecting nondeterministic sql functions
+func TestIsVolatileSQL(t *testing.T) {
+	config := CacheConfig{}
+	assert.True(t, isVolatileSQL("SELECT * FROM users WHERE created_at > now() - interval '1 day'", config))
+	assert.True(t, isVolatileSQL("SELECT CURRENT_TIMESTAMP", config))
+	assert.True(t, isVolatileSQL("SELECT * FROM users ORDER BY RANDOM() LIMIT 1", config))
+	assert.False(t, isVolatileSQL("SELECT * FROM users WHERE name = $1", config))
+	assert.False(t, isVolatileSQL("SELECT snow, known_at FROM users", config))
+	assert.False(t, isVolatileSQL(`SELECT * FROM "users" WHERE "users"."uuid" = $1`, config))
+	assert.False(t, isVolatileSQL(`SELECT now_col, "now" FROM users WHERE uuid = $1`, config))
+	assert.False(t, isVolatileSQL(`SELECT * FROM users WHERE note = 'call now()'`, config))
+	assert.True(t, isVolatileSQL(`SELECT "now" FROM users WHERE created_at < NOW ()`, config))
+
+	config.VolatileFuncs = []string{"my_random"}
+	assert.True(t, isVolatileSQL("SELECT my_random()", config))
+	assert.False(t, isVolatileSQL("SELECT now()", config))
+
+	config.VolatileFuncs = []string{}
+	assert.False(t, isVolatileSQL("SELECT now()", config))
+}
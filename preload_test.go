@@ -0,0 +1,51 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type TestCompany struct {
+	ID        int
+	Name      string
+	Employees []TestEmployee `gorm:"foreignKey:CompanyID"`
+}
+
+type TestEmployee struct {
+	ID        int
+	CompanyID int
+	Name      string
+}
+
+// TestCachePreload tests caching preload queries with their own keys
+func TestCachePreload(t *testing.T) {
+	assert.NoError(t, db.Migrator().DropTable(TestEmployee{}, TestCompany{}))
+	assert.NoError(t, db.AutoMigrate(TestCompany{}, TestEmployee{}))
+	assert.NoError(t, db.Create(&TestCompany{Name: "A", Employees: []TestEmployee{{Name: "a1"}, {Name: "a2"}}}).Error)
+
+	cache := NewGormCache("preload_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "preload:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	for i := 0; i < 2; i++ {
+		var companies []TestCompany
+		assert.NoError(t, db.Session(&gorm.Session{Context: ctx}).Preload("Employees").Find(&companies).Error)
+		assert.Len(t, companies, 1)
+		assert.Len(t, companies[0].Employees, 2)
+	}
+
+	// the primary query and the preload query miss once and hit once
+	assert.Equal(t, int64(2), cache.Stats().Misses)
+	assert.Equal(t, int64(2), cache.Stats().Hits)
+
+	keys, err := rdb.Keys(context.Background(), "preload:test_employees:*").Result()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
@@ -0,0 +1,138 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownQuery is returned for names without a registered query
+var ErrUnknownQuery = errors.New("unknown query")
+
+// Policy is a struct for the cache policy of a registered query
+type Policy struct {
+	TTL  time.Duration // ttl of the cached result, 0 means the ttl of the config
+	Warm bool          // whether the query is run by WarmRegistered
+}
+
+// namedQuery is a registered query with its destination type erased
+type namedQuery struct {
+	policy Policy
+	build  func(db *gorm.DB, dest interface{}) (*gorm.DB, error) // runs the query into dest, nil for a new destination
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]namedQuery)
+)
+
+// Register registers a named query with its cache policy, e.g. for critical queries whose ttls, warming and invalidation
+// are kept in one place. build runs the query on db into dest and returns the result:
+//
+//	grc.Register("top_products", func(db *gorm.DB, dest *[]Product) *gorm.DB {
+//		return db.Order("sales DESC").Limit(10).Find(dest)
+//	}, grc.Policy{TTL: 5 * time.Minute, Warm: true})
+//
+// Queries are run with RunQuery. Register panics if name is already registered, like sql.Register.
+func Register[T any](name string, build func(db *gorm.DB, dest *T) *gorm.DB, policy Policy) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("grc: Register called twice for query " + name)
+	}
+	registry[name] = namedQuery{
+		policy: policy,
+		build: func(db *gorm.DB, dest interface{}) (*gorm.DB, error) {
+			if dest == nil {
+				dest = new(T)
+			}
+			d, ok := dest.(*T)
+			if !ok {
+				return nil, fmt.Errorf("query %s: destination %T, want %T", name, dest, (*T)(nil))
+			}
+			return build(db, d), nil
+		},
+	}
+}
+
+// lookupQuery returns the registered query of name
+func lookupQuery(name string) (namedQuery, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	q, ok := registry[name]
+	if !ok {
+		return q, fmt.Errorf("%w %s", ErrUnknownQuery, name)
+	}
+	return q, nil
+}
+
+// RunQuery runs the registered query of name on db into dest using the cache with the policy of the query
+func RunQuery[T any](db *gorm.DB, name string, dest *T) error {
+	q, err := lookupQuery(name)
+	if err != nil {
+		return err
+	}
+	db, err = q.build(Session(db, TTL(q.policy.TTL)), dest)
+	if err != nil {
+		return err
+	}
+	return db.Error
+}
+
+// WarmRegistered pre-populates the cache with the registered queries of names with Warm, see Warm,
+// no names means all registered queries with Warm in their policy
+func (g *GormCache) WarmRegistered(ctx context.Context, db *gorm.DB, names ...string) error {
+	registryMu.RLock()
+	if len(names) == 0 {
+		for name, q := range registry {
+			if q.policy.Warm {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+	registryMu.RUnlock()
+
+	queries := make([]WarmQuery, len(names))
+	for i, name := range names {
+		q, err := lookupQuery(name)
+		if err != nil {
+			return err
+		}
+		queries[i] = WarmQuery{Name: name, TTL: q.policy.TTL, Query: func(db *gorm.DB) error {
+			db, err := q.build(db, nil)
+			if err != nil {
+				return err
+			}
+			return db.Error
+		}}
+	}
+	return g.Warm(ctx, db, queries...)
+}
+
+// InvalidateQuery deletes the cache entries of the registered queries of names, whose keys are found by building
+// the queries on db in a DryRun session, and publishes the invalidation like InvalidateKey
+func (g *GormCache) InvalidateQuery(ctx context.Context, db *gorm.DB, names ...string) error {
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		q, err := lookupQuery(name)
+		if err != nil {
+			return err
+		}
+		stmt, err := q.build(Session(db.WithContext(ctx).Session(&gorm.Session{DryRun: true})), nil)
+		if err != nil {
+			return err
+		}
+		key, _, _ := g.Explain(stmt)
+		if key == "" {
+			return fmt.Errorf("query %s: not built", name)
+		}
+		keys = append(keys, key)
+	}
+	return g.InvalidateKey(ctx, keys...)
+}
@@ -0,0 +1,52 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestRegistry tests running, warming and invalidating registered queries
+func TestRegistry(t *testing.T) {
+	client := NewMemoryCache()
+	cache := NewGormCache("registry_cache", client, CacheConfig{TTL: time.Minute, Prefix: "registry:"})
+	assert.NoError(t, db.Use(cache))
+	ctx := context.Background()
+
+	Register("registry_latest", func(db *gorm.DB, dest *[]TestUser) *gorm.DB {
+		return db.Where("id > ?", 96).Order("id").Find(dest)
+	}, Policy{TTL: time.Hour, Warm: true})
+	Register("registry_first", func(db *gorm.DB, dest *TestUser) *gorm.DB {
+		return db.Where("id = ?", 9).First(dest)
+	}, Policy{})
+	assert.Panics(t, func() {
+		Register("registry_first", func(db *gorm.DB, dest *TestUser) *gorm.DB { return db }, Policy{})
+	})
+
+	assert.NoError(t, cache.WarmRegistered(ctx, db, "registry_latest"))
+	keys := client.Keys("registry:")
+	assert.Len(t, keys, 1)
+	_, ttl, err := client.GetWithTTL(ctx, keys[0])
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+
+	var users []TestUser
+	assert.NoError(t, RunQuery(db, "registry_latest", &users))
+	assert.Len(t, users, 4)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+
+	var user TestUser
+	assert.NoError(t, RunQuery(db, "registry_first", &user))
+	assert.Equal(t, 9, user.ID)
+	assert.Len(t, client.Keys("registry:"), 2)
+
+	assert.Error(t, RunQuery(db, "registry_first", &users)) // wrong destination
+	assert.ErrorIs(t, RunQuery(db, "registry_missing", &users), ErrUnknownQuery)
+
+	assert.NoError(t, cache.InvalidateQuery(ctx, db, "registry_latest"))
+	assert.NotContains(t, client.Keys("registry:"), keys[0])
+	assert.Len(t, client.Keys("registry:"), 1)
+}
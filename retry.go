@@ -0,0 +1,116 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy is a struct for retry options of an operation
+type RetryPolicy struct {
+	Retries    int                  // retries after the first attempt, 0 means no retries
+	MinBackoff time.Duration        // backoff before the first retry, doubled on each retry, default 10ms
+	MaxBackoff time.Duration        // maximum backoff, default 500ms
+	Retryable  func(err error) bool // reports whether err is transient, nil means IsTransient
+}
+
+// RetryOptions is a struct for retry policies by operation type
+type RetryOptions struct {
+	Get RetryPolicy // used by Get and the reads of optional interfaces, e.g. GetMulti or GetWithTTL
+	Set RetryPolicy // used by Set and the writes of optional interfaces, e.g. SetMulti, Touch or GetOrSet
+	Del RetryPolicy // used by Del and FlushPrefix
+}
+
+// policy returns the retry policy of an optional operation forwarded by a middleware,
+// reads use the Get policy and writes the Set policy, pings are not retried to report failures promptly
+func (o RetryOptions) policy(op string) RetryPolicy {
+	switch op {
+	case "get_multi", "get_multi_with_ttl", "get_with_ttl", "ttl", "get_and_expire", "list_keys":
+		return o.Get
+	case "ping":
+		return RetryPolicy{}
+	default:
+		return o.Set
+	}
+}
+
+// WithRetry returns a Middleware retrying operations failing with transient errors with a jittered exponential backoff
+func WithRetry(options RetryOptions) Middleware {
+	return func(client CacheClient) CacheClient {
+		next := passThrough(client)
+		c := next
+		c.get = func(ctx context.Context, key string) (value interface{}, err error) {
+			err = options.Get.do(ctx, func() error {
+				value, err = next.get(ctx, key)
+				return err
+			})
+			return value, err
+		}
+		c.set = func(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+			return options.Set.do(ctx, func() error { return next.set(ctx, key, value, ttl) })
+		}
+		c.del = func(ctx context.Context, keys ...string) error {
+			return options.Del.do(ctx, func() error { return next.del(ctx, keys...) })
+		}
+		c.flushPrefix = func(ctx context.Context, prefix string) error {
+			return options.Del.do(ctx, func() error { return next.flushPrefix(ctx, prefix) })
+		}
+		c.wrapOptional(next, func(ctx context.Context, op string, f func() error) error {
+			return options.policy(op).do(ctx, f)
+		})
+		return c
+	}
+}
+
+// IsTransient reports whether err is a network error, e.g. a timeout, a reset connection or a closed connection,
+// which a retry may not hit. Cache misses, context errors and ErrCircuitOpen are not transient.
+func IsTransient(err error) bool {
+	if err == nil || isCacheMiss(err) || errors.Is(err, ErrCircuitOpen) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// do calls f and retries it while it fails with a retryable error, until ctx is done
+func (p RetryPolicy) do(ctx context.Context, f func() error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	backoff := p.MinBackoff
+	if backoff <= 0 {
+		backoff = 10 * time.Millisecond
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 500 * time.Millisecond
+	}
+
+	err := f()
+	for i := 0; i < p.Retries && err != nil && retryable(err); i++ {
+		timer := time.NewTimer(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		err = f()
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWithRetry tests retrying transient errors by operation type
+func TestWithRetry(t *testing.T) {
+	ctx := context.Background()
+	var gets, sets int
+	backend := clientFuncs{
+		get: func(ctx context.Context, key string) (interface{}, error) {
+			if gets++; gets < 3 {
+				return nil, io.EOF
+			}
+			return []byte("A"), nil
+		},
+		set: func(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+			sets++
+			return syscall.ECONNRESET
+		},
+	}
+	client := Chain(backend, WithRetry(RetryOptions{
+		Get: RetryPolicy{Retries: 2, MinBackoff: time.Millisecond},
+		Set: RetryPolicy{Retries: 1, MinBackoff: time.Millisecond},
+	}))
+
+	value, err := client.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	assert.Equal(t, 3, gets)
+
+	assert.ErrorIs(t, client.Set(ctx, "a", []byte("A"), time.Minute), syscall.ECONNRESET)
+	assert.Equal(t, 2, sets)
+
+	// misses are not retried
+	gets = 0
+	miss := Chain(clientFuncs{get: func(ctx context.Context, key string) (interface{}, error) {
+		gets++
+		return nil, ErrCacheMiss
+	}}, WithRetry(RetryOptions{Get: RetryPolicy{Retries: 2}}))
+	_, err = miss.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, 1, gets)
+
+	// retries stop when the context is done
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	sets = 0
+	assert.Error(t, client.Set(canceled, "a", []byte("A"), time.Minute))
+	assert.Equal(t, 1, sets)
+}
+
+// TestIsTransient tests classifying transient errors
+func TestIsTransient(t *testing.T) {
+	assert.True(t, IsTransient(io.EOF))
+	assert.True(t, IsTransient(fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	assert.True(t, IsTransient(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.False(t, IsTransient(nil))
+	assert.False(t, IsTransient(ErrCacheMiss))
+	assert.False(t, IsTransient(ErrCircuitOpen))
+	assert.False(t, IsTransient(context.DeadlineExceeded))
+	assert.False(t, IsTransient(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")))
+}
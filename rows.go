@@ -0,0 +1,256 @@
+package grc
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+// rowsEntry is the cached value of a Row or Rows query
+type rowsEntry struct {
+	Columns []string
+	Rows    [][]typedValue
+}
+
+// rowCallback is a callback function for Row, Rows and Scan operations. Row results are cursors,
+// so rows are read into memory, cached, and replayed through replayDB as *sql.Row or *sql.Rows.
+func (g *GormCache) rowCallback(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+
+	config := g.Config()
+	enableCache, reason := g.cacheDecision(db, config)
+	if !config.CacheRows {
+		enableCache, reason = false, "row queries not cached without CacheRows"
+	} else if config.Audit {
+		enableCache, reason = false, "row queries not audited"
+	}
+	if !enableCache {
+		callbacks.RowQuery(db)
+		if config.Debug {
+			g.debugQuery(db, config, "", reason, "skip")
+		}
+		return
+	}
+
+	callbacks.BuildQuerySQL(db)
+	if db.DryRun || db.Error != nil {
+		return
+	}
+	if isVolatileSQL(db.Statement.SQL.String(), config) {
+		callbacks.RowQuery(db) // the sql is built, so it is not built again
+		if config.Debug {
+			g.debugQuery(db, config, "", "volatile sql function", "skip")
+		}
+		return
+	}
+
+	var (
+		entry  *rowsEntry
+		hit    bool
+		err    error
+		result = "refresh" // outcome of the query logged in debug mode
+	)
+	key := g.rowsKey(db, config)
+	if config.Debug {
+		defer func() { g.debugQuery(db, config, key, "", result) }()
+	}
+	if !refreshCache(db) {
+		start := time.Now()
+		entry, hit, err = g.loadRows(db, key, config)
+		event := hookEvent(db, "load", key, start)
+		switch {
+		case err != nil:
+			result = "error"
+			event.Err = err
+			if g.cacheFailed(db, config, event) {
+				return
+			}
+		case hit:
+			result = "hit"
+			g.stats.recordHit()
+			emitHit(db, config, event)
+		default:
+			result = "miss"
+			g.stats.recordMiss()
+			emitMiss(db, config, event)
+		}
+	}
+	if !hit {
+		if entry, err = queryRows(db); err != nil {
+			db.AddError(err)
+			return
+		}
+		start := time.Now()
+		if err = g.setRows(db, key, config, entry); err != nil {
+			event := hookEvent(db, "set", key, start)
+			event.Err = err
+			g.cacheFailed(db, config, event)
+		}
+	}
+
+	// replay cached rows
+	if isRows, ok := db.Get("rows"); ok && isRows.(bool) {
+		db.Statement.Settings.Delete("rows")
+		db.Statement.Dest, db.Error = replayDB.QueryContext(db.Statement.Context, "", entry)
+	} else {
+		db.Statement.Dest = replayDB.QueryRowContext(db.Statement.Context, "", entry)
+	}
+	db.RowsAffected = -1
+}
+
+// rowsKey returns the cache key of a row query, in its own key space as the same sql is cached differently by queryCallback
+func (g *GormCache) rowsKey(db *gorm.DB, config CacheConfig) string {
+	key := g.cacheKey(db, config)
+	prefix := tablePrefix(config, db.Statement.Table)
+	return prefix + "rows:" + key[len(prefix):]
+}
+
+func (g *GormCache) loadRows(db *gorm.DB, key string, config CacheConfig) (*rowsEntry, bool, error) {
+	data, err := g.get(db, key, config)
+	if err != nil || data == nil {
+		return nil, false, err
+	}
+	data, stale := unwrapEntry(data, time.Now())
+	if stale {
+		return nil, false, nil // rows are not revalidated in the background, a stale entry is a miss
+	}
+	entry := &rowsEntry{}
+	if err = g.decode(config, key, data, entry); err != nil {
+		return nil, false, classify(ErrorClassDecode, err)
+	}
+	return entry, true, nil
+}
+
+func (g *GormCache) setRows(db *gorm.DB, key string, config CacheConfig, entry *rowsEntry) error {
+	ttl := g.cacheTTL(db, config)
+	if len(entry.Rows) == 0 {
+		if !config.CacheEmptyResults {
+			return nil
+		}
+		ttl = emptyTTL(config, ttl)
+	}
+	return g.set(db, key, config, entry, ttl)
+}
+
+// queryRows queries the database and reads all rows into memory
+func queryRows(db *gorm.DB) (*rowsEntry, error) {
+	rows, err := db.Statement.ConnPool.QueryContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	entry := &rowsEntry{Columns: columns}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make([]typedValue, len(columns))
+		for i, value := range values {
+			if row[i], err = encodeTypedValue(value); err != nil {
+				return nil, fmt.Errorf("column %s: %w", columns[i], err)
+			}
+		}
+		entry.Rows = append(entry.Rows, row)
+	}
+	return entry, rows.Err()
+}
+
+// replayDB is a database replaying cached rows, the rowsEntry is passed as the only query argument
+var replayDB = sql.OpenDB(replayConnector{})
+
+type replayConnector struct{}
+
+func (replayConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return replayConn{}, nil
+}
+
+func (replayConnector) Driver() driver.Driver {
+	return replayDriver{}
+}
+
+type replayDriver struct{}
+
+func (replayDriver) Open(name string) (driver.Conn, error) {
+	return replayConn{}, nil
+}
+
+var errReplayOnly = errors.New("grc: replay connection only supports queries")
+
+type replayConn struct{}
+
+func (replayConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errReplayOnly
+}
+
+func (replayConn) Close() error {
+	return nil
+}
+
+func (replayConn) Begin() (driver.Tx, error) {
+	return nil, errReplayOnly
+}
+
+// CheckNamedValue accepts the rowsEntry argument as is
+func (replayConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (replayConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 1 {
+		return nil, errReplayOnly
+	}
+	entry, ok := args[0].Value.(*rowsEntry)
+	if !ok {
+		return nil, errReplayOnly
+	}
+	return &replayRows{entry: entry}, nil
+}
+
+// replayRows is driver.Rows of a rowsEntry
+type replayRows struct {
+	entry *rowsEntry
+	next  int
+}
+
+func (r *replayRows) Columns() []string {
+	return r.entry.Columns
+}
+
+func (r *replayRows) Close() error {
+	return nil
+}
+
+func (r *replayRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.entry.Rows) {
+		return io.EOF
+	}
+	for i, v := range r.entry.Rows[r.next] {
+		value, err := decodeTypedValue(v)
+		if err != nil {
+			return err
+		}
+		dest[i] = value
+	}
+	r.next++
+	return nil
+}
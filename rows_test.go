@@ -0,0 +1,72 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestReplayRows tests replaying cached rows as *sql.Rows
+func TestReplayRows(t *testing.T) {
+	id, _ := encodeTypedValue(int64(1))
+	name, _ := encodeTypedValue("A")
+	entry := &rowsEntry{
+		Columns: []string{"id", "name"},
+		Rows:    [][]typedValue{{id, name}},
+	}
+
+	rows, err := replayDB.QueryContext(context.Background(), "", entry)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name"}, columns)
+
+	var user TestUser
+	assert.True(t, rows.Next())
+	assert.NoError(t, rows.Scan(&user.ID, &user.Name))
+	assert.Equal(t, TestUser{ID: 1, Name: "A"}, user)
+	assert.False(t, rows.Next())
+
+	var n int
+	assert.NoError(t, replayDB.QueryRowContext(context.Background(), "", entry).Scan(&n, &user.Name))
+	assert.Equal(t, 1, n)
+}
+
+// TestCacheRows tests caching Row, Rows and Scan queries
+func TestCacheRows(t *testing.T) {
+	cache := NewGormCache("rows_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:       60 * time.Second,
+		Prefix:    "rows:",
+		CacheRows: true,
+		CacheRaw:  true,
+	})
+	assert.NoError(t, db.Use(cache))
+
+	ctx := context.WithValue(context.Background(), UseCacheKey, true)
+	for i := 0; i < 2; i++ {
+		var users []TestUser
+		assert.NoError(t, db.Session(&gorm.Session{Context: ctx}).Raw("SELECT * FROM test_users WHERE id <= ?", 3).Scan(&users).Error)
+		assert.Len(t, users, 3)
+
+		rows, err := db.Session(&gorm.Session{Context: ctx}).Model(&TestUser{}).Where("id <= ?", 3).Rows()
+		assert.NoError(t, err)
+		n := 0
+		for rows.Next() {
+			var user TestUser
+			assert.NoError(t, db.ScanRows(rows, &user))
+			n++
+		}
+		assert.NoError(t, rows.Close())
+		assert.Equal(t, 3, n)
+
+		var name string
+		assert.NoError(t, db.Session(&gorm.Session{Context: ctx}).Model(&TestUser{}).Select("name").Where("id = ?", 1).Row().Scan(&name))
+		assert.Equal(t, "41", name)
+	}
+	assert.Equal(t, int64(3), cache.Stats().Hits)
+}
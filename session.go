@@ -0,0 +1,65 @@
+package grc
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Enable returns a copy of ctx in which queries use the cache
+func Enable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, UseCacheKey, true)
+}
+
+// Disable returns a copy of ctx in which queries do not use the cache
+func Disable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, UseCacheKey, false)
+}
+
+// WithTTL returns a copy of ctx in which queries use the cache with ttl
+func WithTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(Enable(ctx), CacheTTLKey, ttl)
+}
+
+// WithRefresh returns a copy of ctx in which queries skip cache reads but still cache their results
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(Enable(ctx), RefreshCacheKey, true)
+}
+
+// Option configures the cache of queries in a Session
+type Option func(*Cache)
+
+// TTL sets the ttl of cached results
+func TTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.TTL = ttl
+	}
+}
+
+// Refresh skips cache reads but still caches results
+func Refresh() Option {
+	return func(c *Cache) {
+		c.Refresh = true
+	}
+}
+
+// Session returns a new session of db in which queries use the cache,
+// e.g. grc.Session(db, grc.TTL(time.Minute)).Find(&users), the session can be reused for several queries
+func Session(db *gorm.DB, opts ...Option) *gorm.DB {
+	var c Cache
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return db.Clauses(c).Session(&gorm.Session{})
+}
+
+// CachedFind finds the records of T matching conds using the cache with ttl, 0 means the ttl of the config,
+// e.g. users, err := grc.CachedFind[User](ctx, db, time.Minute, "id > ?", 10)
+func CachedFind[T any](ctx context.Context, db *gorm.DB, ttl time.Duration, conds ...interface{}) ([]T, error) {
+	var dest []T
+	if err := Session(db.WithContext(ctx), TTL(ttl)).Find(&dest, conds...).Error; err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
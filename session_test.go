@@ -0,0 +1,77 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestContextHelpers tests building cache contexts
+func TestContextHelpers(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, true, Enable(ctx).Value(UseCacheKey))
+	assert.Equal(t, false, Disable(ctx).Value(UseCacheKey))
+
+	ttlCtx := WithTTL(ctx, time.Minute)
+	assert.Equal(t, true, ttlCtx.Value(UseCacheKey))
+	ttl, ok := contextTTL(ttlCtx, CacheConfig{})
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, ttl)
+
+	refreshCtx := WithRefresh(ctx)
+	assert.Equal(t, true, refreshCtx.Value(UseCacheKey))
+	assert.Equal(t, true, refreshCtx.Value(RefreshCacheKey))
+}
+
+// TestSession tests reusing a session in which queries use the cache
+func TestSession(t *testing.T) {
+	cache := NewGormCache("session_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:    60 * time.Second,
+		Prefix: "session:",
+	})
+	assert.NoError(t, db.Use(cache))
+
+	session := Session(db, TTL(time.Minute))
+	for i := 0; i < 2; i++ {
+		var users []TestUser
+		assert.NoError(t, session.Where("id > ?", 10).Find(&users).Error)
+		assert.Len(t, users, userCount-10)
+
+		var user TestUser
+		assert.NoError(t, session.WithContext(context.Background()).Where("id = ?", 1).First(&user).Error)
+		assert.Equal(t, "41", user.Name)
+	}
+	assert.Equal(t, int64(2), cache.Stats().Misses)
+	assert.Equal(t, int64(2), cache.Stats().Hits)
+
+	var users []TestUser
+	assert.NoError(t, Session(db, Refresh()).Where("id > ?", 10).Find(&users).Error)
+	assert.Equal(t, int64(2), cache.Stats().Hits)
+	assert.Equal(t, int64(3), cache.Stats().Sets)
+}
+
+// TestCachedFind tests finding typed records using the cache
+func TestCachedFind(t *testing.T) {
+	client := NewMemoryCache()
+	cache := NewGormCache("cached_find_cache", client, CacheConfig{Prefix: "cached_find:"})
+	assert.NoError(t, db.Use(cache))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		users, err := CachedFind[TestUser](ctx, db, time.Minute, "id > ?", 95)
+		assert.NoError(t, err)
+		assert.Len(t, users, 5)
+	}
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+	keys := client.Keys("cached_find:")
+	assert.Len(t, keys, 1)
+	_, ttl, err := client.GetWithTTL(ctx, keys[0])
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	_, err = CachedFind[TestUser](ctx, db, 0, "missing_column = ?", 1)
+	assert.Error(t, err)
+}
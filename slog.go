@@ -0,0 +1,39 @@
+//go:build go1.21
+
+package grc
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLogger is a Logger writing records to a *slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing records to logger, nil means slog.Default()
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return slogLogger{logger: logger}
+}
+
+func (l slogLogger) Log(ctx context.Context, level LogLevel, msg string, args ...interface{}) {
+	l.logger.Log(ctx, slogLevel(level), msg, args...)
+}
+
+// slogLevel converts a LogLevel to a slog.Level, LogDefault is slog.LevelInfo
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogDebug:
+		return slog.LevelDebug
+	case LogWarn:
+		return slog.LevelWarn
+	case LogError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
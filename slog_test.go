@@ -0,0 +1,55 @@
+//go:build go1.21
+
+package grc
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSlogLogger tests structured records of cache events
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := &downCache{MemoryCache: NewMemoryCache()}
+	defer client.Close()
+	cache := NewGormCache("slog_cache", client, CacheConfig{
+		TTL:       time.Minute,
+		Logger:    NewSlogLogger(logger),
+		LogLevels: LogLevels{Hit: LogInfo},
+	})
+	assert.NoError(t, db.Use(cache))
+
+	var user TestUser
+	assert.NoError(t, Session(db).Where("id = ?", 4).First(&user).Error)
+	user = TestUser{}
+	assert.NoError(t, Session(db).Where("id = ?", 4).First(&user).Error)
+	atomic.StoreInt32(&client.down, 1)
+	user = TestUser{}
+	assert.NoError(t, Session(db).Where("id = ?", 4).First(&user).Error)
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	var msgs []string
+	for _, record := range records {
+		msgs = append(msgs, record["level"].(string)+" "+record["msg"].(string))
+	}
+	assert.Equal(t, []string{
+		"DEBUG cache miss", "DEBUG cache set", "INFO cache hit", "ERROR cache load failed", "ERROR cache set failed",
+	}, msgs)
+	assert.Equal(t, "test_users", records[0]["table"])
+	assert.Equal(t, records[0]["key"], records[2]["key"])
+	assert.Contains(t, records[2], "latency")
+	assert.Equal(t, errDown.Error(), records[3]["error"])
+}
@@ -0,0 +1,135 @@
+package grc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// entryMagic marks entries with a freshness header, which are written when SoftTTL or StaleTTL is set
+var entryMagic = []byte("grc\x01")
+
+// wrapEntry prefixes data with the time until which it is fresh
+func wrapEntry(data []byte, freshUntil time.Time) []byte {
+	n := len(entryMagic)
+	buf := make([]byte, n+8+len(data))
+	copy(buf, entryMagic)
+	binary.BigEndian.PutUint64(buf[n:], uint64(freshUntil.UnixNano()))
+	copy(buf[n+8:], data)
+	return buf
+}
+
+// unwrapEntry strips the freshness header of data and reports whether data is stale, data without a header is fresh
+func unwrapEntry(data []byte, now time.Time) ([]byte, bool) {
+	data, freshUntil, ok := entryHeader(data)
+	return data, ok && !now.Before(freshUntil)
+}
+
+// entryHeader strips the freshness header of data and returns the time until which data is fresh,
+// ok is false if data has no header
+func entryHeader(data []byte) (payload []byte, freshUntil time.Time, ok bool) {
+	n := len(entryMagic)
+	if len(data) < n+8 || !bytes.Equal(data[:n], entryMagic) {
+		return data, time.Time{}, false
+	}
+	return data[n+8:], time.Unix(0, int64(binary.BigEndian.Uint64(data[n:]))), true
+}
+
+// entryTTL returns how long an entry with ttl is fresh, at most SoftTTL, and how long it is retained in the cache client,
+// StaleTTL after ttl. An entry is revalidated on hits between the two.
+func entryTTL(config CacheConfig, ttl time.Duration) (fresh, hard time.Duration) {
+	if ttl <= 0 {
+		return ttl, ttl // no expiration
+	}
+	fresh, hard = ttl, ttl
+	if config.SoftTTL > 0 && config.SoftTTL < fresh {
+		fresh = config.SoftTTL
+	}
+	if config.StaleTTL > 0 {
+		hard += config.StaleTTL
+	}
+	return fresh, hard
+}
+
+// cacheable reports whether the result of a query may be cached, failed queries are not cached
+// except First without a record if empty results are cached
+func cacheable(db *gorm.DB, config CacheConfig) bool {
+	return db.Error == nil || config.CacheEmptyResults && errors.Is(db.Error, gorm.ErrRecordNotFound)
+}
+
+// revalidate re-executes the query of a stale hit in the background and overwrites its entry,
+// at most one query runs per key at a time
+func (g *GormCache) revalidate(db *gorm.DB, key string, config CacheConfig) {
+	tx, ok := revalidateDB(db)
+	if !ok {
+		return
+	}
+	if _, running := g.revalidating.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer g.revalidating.Delete(key)
+
+		// with a fill lock, only its holder revalidates and the other instances keep serving the stale entry
+		if config.FillLocker != nil {
+			unlock, ok, err := config.FillLocker.Lock(tx.Statement.Context, fillLockKey(key), fillLockTTL(config))
+			if err != nil || !ok {
+				return
+			}
+			defer unlock()
+		}
+
+		g.queryDB(tx)
+		if !cacheable(tx, config) {
+			logMessage(tx.Statement.Context, config, LogWarn, "revalidate cache failed", tx.Error)
+			return
+		}
+		start := time.Now()
+		if err := g.setCache(tx, key, config); err != nil {
+			event := hookEvent(tx, "set", key, start)
+			event.Err = err
+			g.cacheFailed(tx, config, event) // the query of the request is served, the failure policy does not apply
+		}
+	}()
+}
+
+// revalidateDB returns a copy of the query of db with a new destination,
+// its context is detached from the request, which may be canceled before the query completes
+func revalidateDB(db *gorm.DB) (*gorm.DB, bool) {
+	dest, ok := newDest(db.Statement.Dest)
+	if !ok {
+		return nil, false
+	}
+
+	ctx := context.Background()
+	if ttl := db.Statement.Context.Value(CacheTTLKey); ttl != nil {
+		ctx = context.WithValue(ctx, CacheTTLKey, ttl)
+	}
+	tx := db.Session(&gorm.Session{Context: ctx}) // clones sql, vars, clauses and settings of the statement
+	tx.Statement.Dest = dest
+	tx.Statement.ReflectValue = reflect.Indirect(reflect.ValueOf(dest))
+	return tx, true
+}
+
+// newDest returns a new zero destination of the same type as dest
+func newDest(dest interface{}) (interface{}, bool) {
+	rv := reflect.ValueOf(dest)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		ptr := reflect.New(rv.Type().Elem())
+		if ptr.Elem().Kind() == reflect.Map {
+			ptr.Elem().Set(reflect.MakeMap(ptr.Elem().Type()))
+		}
+		return ptr.Interface(), true
+	case reflect.Map:
+		return reflect.MakeMap(rv.Type()).Interface(), true
+	default:
+		return nil, false
+	}
+}
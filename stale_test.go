@@ -0,0 +1,130 @@
+package grc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWrapEntry tests the freshness header of entries
+func TestWrapEntry(t *testing.T) {
+	now := time.Now()
+	data := wrapEntry([]byte(`{"ID":1}`), now.Add(time.Minute))
+
+	unwrapped, stale := unwrapEntry(data, now)
+	assert.Equal(t, []byte(`{"ID":1}`), unwrapped)
+	assert.False(t, stale)
+
+	unwrapped, stale = unwrapEntry(data, now.Add(time.Minute))
+	assert.Equal(t, []byte(`{"ID":1}`), unwrapped)
+	assert.True(t, stale)
+
+	unwrapped, stale = unwrapEntry(wrapEntry([]byte{}, now), now)
+	assert.Empty(t, unwrapped)
+	assert.True(t, stale)
+
+	// entries written without StaleTTL have no header
+	unwrapped, stale = unwrapEntry([]byte(`{"ID":1}`), now)
+	assert.Equal(t, []byte(`{"ID":1}`), unwrapped)
+	assert.False(t, stale)
+
+}
+
+// TestEntryTTL tests the fresh and hard ttl of entries
+func TestEntryTTL(t *testing.T) {
+	testCases := []struct {
+		config      CacheConfig
+		ttl         time.Duration
+		fresh, hard time.Duration
+	}{
+		{CacheConfig{}, time.Minute, time.Minute, time.Minute},
+		{CacheConfig{StaleTTL: time.Minute}, time.Minute, time.Minute, 2 * time.Minute},
+		{CacheConfig{SoftTTL: time.Second}, time.Minute, time.Second, time.Minute},
+		{CacheConfig{SoftTTL: time.Hour}, time.Minute, time.Minute, time.Minute},
+		{CacheConfig{SoftTTL: time.Second, StaleTTL: time.Minute}, time.Minute, time.Second, 2 * time.Minute},
+		{CacheConfig{SoftTTL: time.Second, StaleTTL: time.Minute}, 0, 0, 0},
+	}
+	for _, tc := range testCases {
+		fresh, hard := entryTTL(tc.config, tc.ttl)
+		assert.Equal(t, tc.fresh, fresh, "%+v", tc.config)
+		assert.Equal(t, tc.hard, hard, "%+v", tc.config)
+	}
+}
+
+// TestNewDest tests creating destinations of revalidated queries
+func TestNewDest(t *testing.T) {
+	dest, ok := newDest(&[]TestUser{{ID: 1}})
+	assert.True(t, ok)
+	assert.Equal(t, new([]TestUser), dest)
+
+	dest, ok = newDest(&map[string]interface{}{"id": 1})
+	assert.True(t, ok)
+	assert.Equal(t, &map[string]interface{}{}, dest)
+
+	dest, ok = newDest(map[string]interface{}{"id": 1})
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{}, dest)
+
+	_, ok = newDest(TestUser{})
+	assert.False(t, ok)
+}
+
+// TestStaleWhileRevalidate tests serving stale entries while revalidating them in the background
+func TestStaleWhileRevalidate(t *testing.T) {
+	cache := NewGormCache("stale_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:      100 * time.Millisecond,
+		Prefix:   "stale:",
+		StaleTTL: time.Minute,
+	})
+	assert.NoError(t, db.Use(cache))
+
+	query := func() string {
+		var user TestUser
+		assert.NoError(t, Session(db).Where("id = ?", 2).First(&user).Error)
+		return user.Name
+	}
+
+	assert.Equal(t, "42", query())
+	assert.NoError(t, db.Model(&TestUser{}).Where("id = ?", 2).Update("name", "revalidated").Error)
+	defer db.Model(&TestUser{}).Where("id = ?", 2).Update("name", "42")
+
+	assert.Equal(t, "42", query()) // fresh
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, "42", query()) // stale
+	assert.Equal(t, int64(1), cache.Stats().Stale)
+
+	assert.Eventually(t, func() bool {
+		return cache.Stats().Sets == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "revalidated", query())
+	assert.Equal(t, int64(3), cache.Stats().Hits)
+	assert.Equal(t, int64(1), cache.Stats().Stale)
+}
+
+// TestSoftTTL tests refreshing entries ahead of their expiration
+func TestSoftTTL(t *testing.T) {
+	cache := NewGormCache("soft_cache", NewRedisClient(rdb), CacheConfig{
+		TTL:     time.Minute,
+		Prefix:  "soft:",
+		SoftTTL: 100 * time.Millisecond,
+	})
+	assert.NoError(t, db.Use(cache))
+
+	query := func() string {
+		var user TestUser
+		assert.NoError(t, Session(db).Where("id = ?", 3).First(&user).Error)
+		return user.Name
+	}
+
+	assert.Equal(t, "43", query())
+	assert.NoError(t, db.Model(&TestUser{}).Where("id = ?", 3).Update("name", "refreshed").Error)
+	defer db.Model(&TestUser{}).Where("id = ?", 3).Update("name", "43")
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, "43", query())
+	assert.Eventually(t, func() bool {
+		return cache.Stats().Sets == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "refreshed", query())
+}
@@ -0,0 +1,168 @@
+package grc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a struct for cache statistics
+type Stats struct {
+	Hits      int64                 // number of queries served from cache
+	Misses    int64                 // number of queries not found in cache
+	Sets      int64                 // number of entries written to cache
+	Bytes     int64                 // total bytes written to cache
+	Errors    int64                 // number of failed cache reads and writes
+	Oversized int64                 // number of results not cached as they exceed MaxValueBytes
+	Stale     int64                 // number of hits served stale while being revalidated, also counted in Hits
+	Tables    map[string]TableStats // statistics by table name, raw queries are counted under ""
+	Stages    map[string]StageStats // statistics by codec stage name, serialization is counted under the codec name
+}
+
+// TableStats is a struct for cache statistics of a table
+type TableStats struct {
+	Sets  int64 // number of entries written to cache
+	Bytes int64 // total bytes written to cache, an estimate of memory consumption in the backend
+}
+
+// StageStats is a struct for statistics of a codec stage
+type StageStats struct {
+	Encodes  int64         // number of encode calls
+	Decodes  int64         // number of decode calls
+	Errors   int64         // number of failed calls
+	BytesIn  int64         // total input bytes
+	BytesOut int64         // total output bytes
+	Duration time.Duration // total time spent
+}
+
+// stats collects cache statistics of a GormCache
+type stats struct {
+	hits      int64
+	misses    int64
+	sets      int64
+	bytes     int64
+	errors    int64
+	oversized int64
+	stale     int64
+	mu        sync.Mutex
+	tables    map[string]*TableStats
+	stages    map[string]*StageStats
+}
+
+func (s *stats) recordHit() {
+	atomic.AddInt64(&s.hits, 1)
+}
+
+func (s *stats) recordMiss() {
+	atomic.AddInt64(&s.misses, 1)
+}
+
+func (s *stats) recordError() {
+	atomic.AddInt64(&s.errors, 1)
+}
+
+func (s *stats) recordOversized() {
+	atomic.AddInt64(&s.oversized, 1)
+}
+
+func (s *stats) recordStale() {
+	atomic.AddInt64(&s.stale, 1)
+}
+
+func (s *stats) recordSet(table string, size int) {
+	atomic.AddInt64(&s.sets, 1)
+	atomic.AddInt64(&s.bytes, int64(size))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tables == nil {
+		s.tables = make(map[string]*TableStats)
+	}
+	ts, ok := s.tables[table]
+	if !ok {
+		ts = &TableStats{}
+		s.tables[table] = ts
+	}
+	ts.Sets++
+	ts.Bytes += int64(size)
+}
+
+func (s *stats) recordStage(name string, encode bool, in, out int, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stages == nil {
+		s.stages = make(map[string]*StageStats)
+	}
+	ss, ok := s.stages[name]
+	if !ok {
+		ss = &StageStats{}
+		s.stages[name] = ss
+	}
+	if encode {
+		ss.Encodes++
+	} else {
+		ss.Decodes++
+	}
+	if err != nil {
+		ss.Errors++
+	}
+	ss.BytesIn += int64(in)
+	ss.BytesOut += int64(out)
+	ss.Duration += d
+}
+
+func (s *stats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tables := make(map[string]TableStats, len(s.tables))
+	for table, ts := range s.tables {
+		tables[table] = *ts
+	}
+	stages := make(map[string]StageStats, len(s.stages))
+	for name, ss := range s.stages {
+		stages[name] = *ss
+	}
+	return Stats{
+		Hits:      atomic.LoadInt64(&s.hits),
+		Misses:    atomic.LoadInt64(&s.misses),
+		Sets:      atomic.LoadInt64(&s.sets),
+		Bytes:     atomic.LoadInt64(&s.bytes),
+		Errors:    atomic.LoadInt64(&s.errors),
+		Oversized: atomic.LoadInt64(&s.oversized),
+		Stale:     atomic.LoadInt64(&s.stale),
+		Tables:    tables,
+		Stages:    stages,
+	}
+}
+
+func (s *stats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, counter := range []*int64{&s.hits, &s.misses, &s.sets, &s.bytes, &s.errors, &s.oversized, &s.stale} {
+		atomic.StoreInt64(counter, 0)
+	}
+	s.tables = nil
+	s.stages = nil
+}
+
+// HitRatio returns the ratio of hits to hits and misses, 0 if there were none
+func (s Stats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// Stats returns the cache statistics
+func (g *GormCache) Stats() Stats {
+	return g.stats.snapshot()
+}
+
+// ResetStats resets the cache statistics to zero, e.g. to measure the effect of a config change
+func (g *GormCache) ResetStats() {
+	g.stats.reset()
+}
@@ -0,0 +1,48 @@
+package grc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStats tests collecting counters and written bytes by table
+func TestStats(t *testing.T) {
+	var s stats
+	s.recordHit()
+	s.recordMiss()
+	s.recordMiss()
+	s.recordError()
+	s.recordSet("users", 10)
+	s.recordSet("users", 20)
+	s.recordSet("", 5)
+
+	snapshot := s.snapshot()
+	assert.Equal(t, int64(1), snapshot.Hits)
+	assert.Equal(t, int64(2), snapshot.Misses)
+	assert.Equal(t, int64(3), snapshot.Sets)
+	assert.Equal(t, int64(35), snapshot.Bytes)
+	assert.Equal(t, int64(1), snapshot.Errors)
+	assert.Equal(t, TableStats{Sets: 2, Bytes: 30}, snapshot.Tables["users"])
+	assert.Equal(t, TableStats{Sets: 1, Bytes: 5}, snapshot.Tables[""])
+}
+
+// TestStatsReset tests the hit ratio and resetting the counters
+func TestStatsReset(t *testing.T) {
+	cache := NewGormCache("my_cache", nil, CacheConfig{})
+	assert.Equal(t, float64(0), cache.Stats().HitRatio())
+
+	cache.stats.recordHit()
+	cache.stats.recordHit()
+	cache.stats.recordHit()
+	cache.stats.recordMiss()
+	cache.stats.recordSet("users", 10)
+	cache.stats.recordStage("json", true, 0, 10, 0, nil)
+	assert.Equal(t, 0.75, cache.Stats().HitRatio())
+
+	cache.ResetStats()
+	stats := cache.Stats()
+	assert.Equal(t, Stats{Tables: map[string]TableStats{}, Stages: map[string]StageStats{}}, stats)
+	cache.stats.recordSet("users", 5)
+	assert.Equal(t, TableStats{Sets: 1, Bytes: 5}, cache.Stats().Tables["users"])
+}
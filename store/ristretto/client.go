@@ -0,0 +1,75 @@
+// Package ristretto provides a grc cache client backed by ristretto, a local cache with TinyLFU admission and cost-based eviction
+package ristretto
+
+import (
+	"context"
+	"time"
+
+	dgraph "github.com/dgraph-io/ristretto"
+	"github.com/evangwt/grc"
+)
+
+// Client is a grc.CacheClient backed by a ristretto cache, the cost of an entry is the size of its value
+type Client struct {
+	cache *dgraph.Cache
+}
+
+// NewClient returns a new Client instance using cache, which should not be used for other values
+func NewClient(cache *dgraph.Cache) *Client {
+	return &Client{cache: cache}
+}
+
+// New returns a new Client instance with a ristretto cache of at most maxCost bytes of values
+func New(maxCost int64) (*Client, error) {
+	cache, err := dgraph.NewCache(&dgraph.Config{
+		NumCounters: maxCost / 100, // ~10x the number of entries of 1KB, as recommended by ristretto
+		MaxCost:     maxCost,
+		BufferItems: 64,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(cache), nil
+}
+
+// Get gets value from ristretto by key, returns grc.ErrCacheMiss if the key does not exist or is expired
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	value, ok := c.cache.Get(key)
+	if !ok {
+		return nil, grc.ErrCacheMiss
+	}
+	return value, nil
+}
+
+// Set sets value to ristretto by key with ttl using json encoding, ttl <= 0 means no expiration.
+// Sets are buffered and may be rejected by the admission policy, so a following Get may miss.
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := grc.ValueBytes(value)
+	if err != nil {
+		return err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	c.cache.SetWithTTL(key, data, int64(len(data)), ttl)
+	return nil
+}
+
+// Del deletes keys from ristretto
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		c.cache.Del(key)
+	}
+	return nil
+}
+
+// Wait waits until buffered sets are applied
+func (c *Client) Wait() {
+	c.cache.Wait()
+}
+
+// Close stops the ristretto cache
+func (c *Client) Close() error {
+	c.cache.Close()
+	return nil
+}
@@ -0,0 +1,224 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// TieredCache is a two-tier cache client, a small local L1 cache in front of a remote L2 cache, e.g. redis
+type TieredCache struct {
+	l1    CacheClient
+	l2    CacheClient
+	l1TTL time.Duration
+}
+
+// DefaultL1TTL is the l1 ttl of a TieredCache created with l1TTL <= 0
+const DefaultL1TTL = 5 * time.Second
+
+// NewTieredCache returns a new TieredCache instance, entries are kept in l1 for at most l1TTL
+// and at most their remaining ttl in l2, l1TTL <= 0 means DefaultL1TTL
+func NewTieredCache(l1 CacheClient, l2 CacheClient, l1TTL time.Duration) *TieredCache {
+	if l1TTL <= 0 {
+		l1TTL = DefaultL1TTL // l1 entries without expiration would outlive their l2 entries
+	}
+	return &TieredCache{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: l1TTL,
+	}
+}
+
+// Get gets value from l1, then from l2 and promotes l2 hits to l1,
+// for at most their remaining ttl in l2 if l2 is a CacheClientExt
+func (t *TieredCache) Get(ctx context.Context, key string) (interface{}, error) {
+	if value, err := t.l1.Get(ctx, key); err == nil && value != nil {
+		return value, nil
+	}
+
+	value, ttl, err := getWithTTL(ctx, t.l2, key)
+	if err != nil || value == nil {
+		return value, err
+	}
+
+	// promote to l1, a failure only costs another l2 read
+	_ = t.l1.Set(ctx, key, value, t.localTTL(ttl))
+	return value, nil
+}
+
+// GetAndExpire gets value like Get and refreshes its ttl in l2 if l2 is an ExpiringGetter,
+// l1 hits do not refresh l2, which is refreshed on the next l1 miss at the latest after l1TTL
+func (t *TieredCache) GetAndExpire(ctx context.Context, key string, ttl time.Duration) (interface{}, error) {
+	getter, ok := t.l2.(ExpiringGetter)
+	if !ok {
+		return t.Get(ctx, key)
+	}
+	if value, err := t.l1.Get(ctx, key); err == nil && value != nil {
+		return value, nil
+	}
+
+	value, err := getter.GetAndExpire(ctx, key, ttl)
+	if errors.Is(err, ErrNotSupported) {
+		return t.Get(ctx, key)
+	}
+	if err != nil || value == nil {
+		return value, err
+	}
+	_ = t.l1.Set(ctx, key, value, t.localTTL(ttl))
+	return value, nil
+}
+
+// GetWithTTL gets value and its remaining ttl from l2, which holds the ttl of entries, and promotes it to l1,
+// returns ErrNotSupported if l2 is not a CacheClientExt
+func (t *TieredCache) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	ext, ok := t.l2.(CacheClientExt)
+	if !ok {
+		return nil, 0, ErrNotSupported
+	}
+	value, ttl, err := ext.GetWithTTL(ctx, key)
+	if err != nil || value == nil {
+		return value, ttl, err
+	}
+	_ = t.l1.Set(ctx, key, value, t.localTTL(ttl))
+	return value, ttl, nil
+}
+
+// Touch sets the ttl of a key in l2, and in l1 capped by l1TTL, returns ErrNotSupported if l2 is not a CacheClientExt
+func (t *TieredCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	ext, ok := t.l2.(CacheClientExt)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := ext.Touch(ctx, key, ttl); err != nil {
+		return err
+	}
+	if local, ok := t.l1.(CacheClientExt); ok {
+		_ = local.Touch(ctx, key, t.localTTL(ttl))
+	}
+	return nil
+}
+
+// TTL gets the remaining ttl of a key in l2, returns ErrNotSupported if l2 is not a CacheClientExt
+func (t *TieredCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ext, ok := t.l2.(CacheClientExt)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return ext.TTL(ctx, key)
+}
+
+// GetMulti gets values from l1, then the missing ones from l2 in one batch and promotes l2 hits to l1
+// like Get, for at most their remaining ttl in l2 if l2 is a MultiTTLGetter, else for l1TTL.
+// The values are in the order of keys and nil for missing keys.
+func (t *TieredCache) GetMulti(ctx context.Context, keys ...string) ([]interface{}, error) {
+	values, err := getMulti(ctx, t.l1, keys...)
+	if err != nil {
+		values = make([]interface{}, len(keys)) // l1 failures fall back to l2
+	}
+
+	var (
+		missing []string
+		indexes []int
+	)
+	for i, value := range values {
+		if value == nil {
+			missing = append(missing, keys[i])
+			indexes = append(indexes, i)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	l2Values, ttls, err := getMultiWithTTL(ctx, t.l2, missing...)
+	if err != nil {
+		return nil, err
+	}
+	promoted := make(map[time.Duration]map[string]interface{}) // l1 ttl => values
+	for j, value := range l2Values {
+		if value == nil {
+			continue
+		}
+		values[indexes[j]] = value
+		ttl := t.localTTL(ttls[j])
+		if promoted[ttl] == nil {
+			promoted[ttl] = make(map[string]interface{})
+		}
+		promoted[ttl][missing[j]] = value
+	}
+	for ttl, batch := range promoted {
+		// promote to l1, a failure only costs another l2 read
+		_ = setMulti(ctx, t.l1, batch, ttl)
+	}
+	return values, nil
+}
+
+// SetMulti sets values to l2 and l1 in batches, the l1 ttl is capped by l1TTL
+func (t *TieredCache) SetMulti(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if err := setMulti(ctx, t.l2, values, ttl); err != nil {
+		return err
+	}
+	return setMulti(ctx, t.l1, values, t.localTTL(ttl))
+}
+
+// Set sets value to l2 and l1, the l1 ttl is capped by l1TTL
+func (t *TieredCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := t.l2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	return t.l1.Set(ctx, key, value, t.localTTL(ttl))
+}
+
+// localTTL caps ttl by l1TTL
+func (t *TieredCache) localTTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < t.l1TTL {
+		return ttl
+	}
+	return t.l1TTL
+}
+
+// Del deletes keys from l2 and l1
+func (t *TieredCache) Del(ctx context.Context, keys ...string) error {
+	deleter, ok := t.l2.(KeyDeleter)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := deleter.Del(ctx, keys...); err != nil {
+		return err
+	}
+	t.EvictLocal(keys...)
+	return nil
+}
+
+// FlushPrefix deletes all keys under prefix from l2 and l1, returns ErrNotSupported if l2 is not a PrefixFlusher.
+// If l1 is not a PrefixFlusher, its entries expire after l1TTL.
+func (t *TieredCache) FlushPrefix(ctx context.Context, prefix string) error {
+	flusher, ok := t.l2.(PrefixFlusher)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := flusher.FlushPrefix(ctx, prefix); err != nil {
+		return err
+	}
+	if flusher, ok := t.l1.(PrefixFlusher); ok {
+		_ = flusher.FlushPrefix(ctx, prefix)
+	}
+	return nil
+}
+
+// ListKeys lists at most limit keys under prefix in l2, returns ErrNotSupported if l2 is not a KeyLister
+func (t *TieredCache) ListKeys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	lister, ok := t.l2.(KeyLister)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return lister.ListKeys(ctx, prefix, limit)
+}
+
+// EvictLocal deletes keys from l1 only, e.g. as the callback of a KeyspaceListener
+func (t *TieredCache) EvictLocal(keys ...string) {
+	if deleter, ok := t.l1.(KeyDeleter); ok {
+		_ = deleter.Del(context.Background(), keys...)
+	}
+}
@@ -0,0 +1,144 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTieredCache tests reading through and promoting to l1
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewMemoryCache(), NewMemoryCache()
+	defer l1.Close()
+	defer l2.Close()
+	cache := NewTieredCache(l1, l2, time.Minute)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), time.Hour))
+	assert.Equal(t, []byte("A"), l1.shard("a").items["a"].value)
+	assert.Equal(t, []byte("A"), l2.shard("a").items["a"].value)
+
+	// promote l2 hit
+	assert.NoError(t, l2.Set(ctx, "b", []byte("B"), time.Hour))
+	value, err := cache.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("B"), value)
+	assert.Equal(t, []byte("B"), l1.shard("b").items["b"].value)
+
+	// evict l1 only
+	cache.EvictLocal("b")
+	_, err = l1.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, cache.Del(ctx, "a"))
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+// TestTieredCacheGetAndExpire tests refreshing the ttl of l2 entries on l1 misses
+func TestTieredCacheGetAndExpire(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewMemoryCache(), NewMemoryCache()
+	defer l1.Close()
+	defer l2.Close()
+	cache := NewTieredCache(l1, l2, time.Minute)
+
+	assert.NoError(t, l2.Set(ctx, "a", []byte("A"), time.Millisecond))
+	value, err := cache.GetAndExpire(ctx, "a", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	assert.True(t, l2.shard("a").items["a"].expireAt.After(time.Now().Add(time.Minute)))
+	assert.True(t, l1.shard("a").items["a"].expireAt.Before(time.Now().Add(time.Minute+time.Second)))
+
+	_, err = cache.GetAndExpire(ctx, "missing", time.Hour)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+// TestTieredCacheExt tests ttl introspection and touch of the tiered cache
+func TestTieredCacheExt(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewMemoryCache(), NewMemoryCache()
+	defer l1.Close()
+	defer l2.Close()
+	var cache CacheClientExt = NewTieredCache(l1, l2, time.Minute)
+
+	assert.NoError(t, l2.Set(ctx, "a", []byte("A"), time.Hour))
+	value, ttl, err := cache.GetWithTTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	assert.Greater(t, ttl, time.Minute)
+	assert.Equal(t, []byte("A"), l1.shard("a").items["a"].value)
+
+	assert.NoError(t, cache.Touch(ctx, "a", 2*time.Hour))
+	ttl, err = cache.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Hour)
+	assert.True(t, l1.shard("a").items["a"].expireAt.Before(time.Now().Add(time.Minute+time.Second)))
+
+	_, err = NewTieredCache(l1, newMapClient(), time.Minute).TTL(ctx, "a")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+// TestTieredCacheFlushPrefix tests deleting all keys under a prefix from both tiers
+func TestTieredCacheFlushPrefix(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewMemoryCache(), NewMemoryCache()
+	defer l1.Close()
+	defer l2.Close()
+	cache := NewTieredCache(l1, l2, time.Minute)
+
+	assert.NoError(t, cache.Set(ctx, "users:1", []byte("A"), time.Hour))
+	assert.NoError(t, cache.Set(ctx, "orders:1", []byte("B"), time.Hour))
+	assert.NoError(t, cache.FlushPrefix(ctx, "users:"))
+	assert.Equal(t, []string{"orders:1"}, l1.Keys(""))
+	assert.Equal(t, []string{"orders:1"}, l2.Keys(""))
+
+	assert.ErrorIs(t, NewTieredCache(l1, newMapClient(), time.Minute).FlushPrefix(ctx, "users:"), ErrNotSupported)
+}
+
+// TestTieredCacheL1TTL tests bounding the ttl of l1 entries by the default l1 ttl and the remaining l2 ttl
+func TestTieredCacheL1TTL(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewMemoryCache(), NewMemoryCache()
+	defer l1.Close()
+	defer l2.Close()
+	cache := NewTieredCache(l1, l2, 0)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("A"), 0))
+	ttl, err := l1.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.InDelta(t, DefaultL1TTL, ttl, float64(time.Second))
+
+	// promoted for at most the remaining l2 ttl
+	assert.NoError(t, l2.Set(ctx, "b", []byte("B"), 2*time.Second))
+	_, err = cache.Get(ctx, "b")
+	assert.NoError(t, err)
+	ttl, err = l1.TTL(ctx, "b")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, 2*time.Second)
+
+	assert.NoError(t, l2.Set(ctx, "c", []byte("C"), 0))
+	_, err = cache.Get(ctx, "c")
+	assert.NoError(t, err)
+	ttl, err = l1.TTL(ctx, "c")
+	assert.NoError(t, err)
+	assert.InDelta(t, DefaultL1TTL, ttl, float64(time.Second))
+
+	// GetMulti promotes like Get, also through middlewares
+	cache = NewTieredCache(l1, Chain(l2, WithRetry(RetryOptions{})), 0)
+	assert.NoError(t, l2.Set(ctx, "d", []byte("D"), 2*time.Second))
+	assert.NoError(t, l2.Set(ctx, "e", []byte("E"), 0))
+	values, err := cache.GetMulti(ctx, "d", "e", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]byte("D"), []byte("E"), nil}, values)
+	ttl, err = l1.TTL(ctx, "d")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, 2*time.Second)
+	ttl, err = l1.TTL(ctx, "e")
+	assert.NoError(t, err)
+	assert.InDelta(t, DefaultL1TTL, ttl, float64(time.Second))
+}
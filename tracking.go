@@ -0,0 +1,154 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// trackingInvalidateChannel is the channel redis redirects invalidation messages of client side caching to
+const trackingInvalidateChannel = "__redis__:invalidate"
+
+// TrackingListener uses redis client side caching to evict keys under a prefix from local caches,
+// e.g. the L1 cache of a TieredCache, as soon as they are modified or deleted in redis by any client.
+//
+// It enables CLIENT TRACKING in broadcasting mode for the prefix and redirects the invalidation messages
+// to a subscribed connection, which requires redis 6 or later. When invalidation messages may have been lost,
+// e.g. after a reconnect or a FLUSHALL, the whole local cache must be dropped.
+type TrackingListener struct {
+	client  *redis.Client
+	prefix  string
+	onEvict func(key string)
+	onFlush func()
+}
+
+// NewTrackingListener returns a new TrackingListener instance, onEvict is called with every key under prefix
+// modified, expired, evicted or deleted in redis, and onFlush when invalidations may have been lost
+func NewTrackingListener(client *redis.Client, prefix string, onEvict func(key string), onFlush func()) *TrackingListener {
+	return &TrackingListener{
+		client:  client,
+		prefix:  prefix,
+		onEvict: onEvict,
+		onFlush: onFlush,
+	}
+}
+
+// Listen enables tracking and dispatches invalidation messages until ctx is done
+func (l *TrackingListener) Listen(ctx context.Context) error {
+	// the subscriber records the id of its connection, which tracking redirects to
+	var subscriberID int64
+	options := *l.client.Options()
+	onConnect := options.OnConnect
+	options.OnConnect = func(ctx context.Context, conn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, conn); err != nil {
+				return err
+			}
+		}
+		id, err := conn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+		atomic.StoreInt64(&subscriberID, id)
+		return nil
+	}
+	subscriber := redis.NewClient(&options)
+	defer subscriber.Close()
+
+	pubsub := subscriber.Subscribe(ctx, trackingInvalidateChannel)
+	defer pubsub.Close()
+
+	// wait for subscription confirmation
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	var (
+		tracker   *redis.Client
+		trackedID int64
+		connects  int64 // connections of tracker, tracking is lost when it reconnects
+	)
+	defer func() {
+		if tracker != nil {
+			tracker.Close()
+		}
+	}()
+
+	for {
+		if id := atomic.LoadInt64(&subscriberID); id != trackedID {
+			// the subscriber reconnected, invalidations sent meanwhile are lost
+			if tracker != nil {
+				tracker.Close()
+				l.onFlush()
+			}
+			atomic.StoreInt64(&connects, 0)
+			tracker = l.newTracker(id, &connects)
+			trackedID = id
+			l.ping(ctx, tracker, &connects) // connects and enables tracking
+		}
+
+		msg, err := pubsub.ReceiveTimeout(ctx, time.Second)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				l.ping(ctx, tracker, &connects) // keep the tracking connection alive
+				continue
+			}
+			// a FLUSHALL is sent as a message without keys, which go-redis fails to parse, or the connection broke
+			l.onFlush()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		}
+
+		if msg, ok := msg.(*redis.Message); ok && msg.Channel == trackingInvalidateChannel {
+			for _, key := range msg.PayloadSlice {
+				l.onEvict(key)
+			}
+		}
+	}
+}
+
+// ping pings tracker, which reconnects with tracking enabled if its connection was closed,
+// calls onFlush if tracking was lost
+func (l *TrackingListener) ping(ctx context.Context, tracker *redis.Client, connects *int64) {
+	before := atomic.LoadInt64(connects)
+	err := tracker.Ping(ctx).Err()
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil || (before > 0 && atomic.LoadInt64(connects) != before) {
+		l.onFlush()
+	}
+}
+
+// newTracker returns a client of one connection with tracking of the prefix redirected to the subscriber connection id
+func (l *TrackingListener) newTracker(id int64, connects *int64) *redis.Client {
+	options := *l.client.Options()
+	options.PoolSize = 1
+	options.MinIdleConns = 0
+	options.IdleTimeout = -1 // the connection must stay open to keep tracking enabled
+	onConnect := options.OnConnect
+	options.OnConnect = func(ctx context.Context, conn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, conn); err != nil {
+				return err
+			}
+		}
+		atomic.AddInt64(connects, 1)
+		cmd := redis.NewStatusCmd(ctx, "CLIENT", "TRACKING", "ON", "REDIRECT", id, "BCAST", "PREFIX", l.prefix)
+		_ = conn.Process(ctx, cmd)
+		return cmd.Err()
+	}
+	return redis.NewClient(&options)
+}
@@ -0,0 +1,33 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTrackingListener tests receiving invalidations of keys under the prefix with client side caching
+func TestTrackingListener(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	evicted := make(chan string, 1)
+	listener := NewTrackingListener(rdb, "tracking:", func(key string) {
+		evicted <- key
+	}, func() {})
+	go listener.Listen(ctx)
+	time.Sleep(100 * time.Millisecond) // wait for subscription and tracking
+
+	assert.NoError(t, rdb.Set(ctx, "other:a", "A", time.Minute).Err())
+	assert.NoError(t, rdb.Set(ctx, "tracking:a", "A", time.Minute).Err())
+
+	select {
+	case key := <-evicted:
+		assert.Equal(t, "tracking:a", key)
+	case <-time.After(time.Second):
+		t.Fatal("no invalidation received")
+	}
+	assert.NoError(t, rdb.Del(ctx, "other:a", "tracking:a").Err())
+}
@@ -0,0 +1,57 @@
+package grc
+
+import (
+	"context"
+	"time"
+)
+
+// TypedClient is a type-safe wrapper of a CacheClient for values of T, e.g. to cache data outside gorm
+// in the same backend. Values are serialized with a codec, stages like compression can be added to the client
+// with Chain and WithStage.
+type TypedClient[T any] struct {
+	client CacheClient
+	codec  Codec
+}
+
+// Typed returns a new TypedClient instance for values of T on client using the json codec,
+// e.g. sessions := grc.Typed[Session](grc.NewRedisClient(rdb))
+func Typed[T any](client CacheClient) *TypedClient[T] {
+	return TypedWithCodec[T](client, JSONCodec{})
+}
+
+// TypedWithCodec returns a new TypedClient instance for values of T on client using codec
+func TypedWithCodec[T any](client CacheClient, codec Codec) *TypedClient[T] {
+	return &TypedClient[T]{client: client, codec: codec}
+}
+
+// Get gets the value of key, returns ErrCacheMiss if the key does not exist
+func (c *TypedClient[T]) Get(ctx context.Context, key string) (T, error) {
+	var v T
+	value, err := c.client.Get(ctx, key)
+	if isCacheMiss(err) || (err == nil && value == nil) {
+		return v, ErrCacheMiss
+	}
+	if err != nil {
+		return v, err
+	}
+	data, err := ValueBytes(value)
+	if err != nil {
+		return v, err
+	}
+	err = c.codec.Unmarshal(data, &v)
+	return v, err
+}
+
+// Set sets the value of key with ttl, ttl <= 0 means no expiration
+func (c *TypedClient[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, key, data, ttl)
+}
+
+// Del deletes keys, returns ErrNotSupported if the client does not implement KeyDeleter
+func (c *TypedClient[T]) Del(ctx context.Context, keys ...string) error {
+	return invalidateKeys(ctx, c.client, keys)
+}
@@ -0,0 +1,43 @@
+package grc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedSession struct {
+	UserID int
+	Roles  []string
+}
+
+// TestTypedClient tests getting and setting typed values with codecs
+func TestTypedClient(t *testing.T) {
+	ctx := context.Background()
+	client := NewMemoryCache()
+
+	for _, sessions := range []*TypedClient[typedSession]{
+		Typed[typedSession](client),
+		TypedWithCodec[typedSession](Chain(client, WithCompression(1)), MsgpackCodec{}),
+	} {
+		_, err := sessions.Get(ctx, "typed:a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		want := typedSession{UserID: 1, Roles: []string{"admin"}}
+		assert.NoError(t, sessions.Set(ctx, "typed:a", want, time.Minute))
+		got, err := sessions.Get(ctx, "typed:a")
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+
+		assert.NoError(t, sessions.Del(ctx, "typed:a"))
+		_, err = sessions.Get(ctx, "typed:a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	}
+
+	assert.NoError(t, client.Set(ctx, "typed:b", []byte("not json"), 0))
+	_, err := Typed[typedSession](client).Get(ctx, "typed:b")
+	assert.Error(t, err)
+	assert.ErrorIs(t, Typed[int](newMapClient()).Del(ctx, "typed:b"), ErrNotSupported)
+}
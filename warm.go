@@ -0,0 +1,43 @@
+package grc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WarmQuery is a query executed by Warm to pre-populate the cache
+type WarmQuery struct {
+	Name  string                  // name of the query in logs and errors
+	TTL   time.Duration           // ttl of the cached result, 0 means the ttl of the config
+	Query func(db *gorm.DB) error // runs the query on db, e.g. return db.Where("featured").Find(&[]Product{}).Error
+}
+
+// Warm executes queries to pre-populate the cache, e.g. at startup to avoid latency spikes after deploys
+// or on demand to re-prime entries. The queries skip cache reads and overwrite their entries.
+// db must use the cache as a plugin, a failed query does not stop the others and the first error is returned.
+func (g *GormCache) Warm(ctx context.Context, db *gorm.DB, queries ...WarmQuery) error {
+	var (
+		firstErr error
+		failed   int
+	)
+	for _, q := range queries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := q.Query(Session(db.WithContext(ctx), TTL(q.TTL), Refresh()))
+		if err == nil {
+			continue
+		}
+		logMessage(ctx, g.Config(), LogWarn, "warm query "+q.Name+" failed", err)
+		if failed++; firstErr == nil {
+			firstErr = fmt.Errorf("warm query %s: %w", q.Name, err)
+		}
+	}
+	if failed > 1 {
+		return fmt.Errorf("%d of %d warm queries failed, %w", failed, len(queries), firstErr)
+	}
+	return firstErr
+}
@@ -0,0 +1,50 @@
+package grc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestWarm tests pre-populating the cache with warm queries, which overwrite existing entries
+func TestWarm(t *testing.T) {
+	client := NewMemoryCache()
+	cache := NewGormCache("warm_cache", client, CacheConfig{TTL: time.Minute, Prefix: "warm:"})
+	assert.NoError(t, db.Use(cache))
+	ctx := context.Background()
+
+	failure := errors.New("failure")
+	err := cache.Warm(ctx, db,
+		WarmQuery{Name: "recent", Query: func(db *gorm.DB) error {
+			return db.Where("id > ?", 97).Find(&[]TestUser{}).Error
+		}},
+		WarmQuery{Name: "first", TTL: time.Hour, Query: func(db *gorm.DB) error {
+			return db.Where("id = ?", 8).First(&TestUser{}).Error
+		}},
+		WarmQuery{Name: "broken", Query: func(db *gorm.DB) error {
+			return failure
+		}},
+	)
+	assert.ErrorIs(t, err, failure)
+	assert.Contains(t, err.Error(), "broken")
+	keys := client.Keys("warm:")
+	assert.Len(t, keys, 2)
+
+	// served from cache
+	stats := cache.Stats()
+	var users []TestUser
+	assert.NoError(t, Session(db).Where("id > ?", 97).Find(&users).Error)
+	assert.Len(t, users, 3)
+	assert.Equal(t, stats.Hits+1, cache.Stats().Hits)
+
+	// warming again overwrites entries without reading them
+	assert.NoError(t, cache.Warm(ctx, db, WarmQuery{Name: "recent", Query: func(db *gorm.DB) error {
+		return db.Where("id > ?", 97).Find(&[]TestUser{}).Error
+	}}))
+	assert.Equal(t, stats.Hits+1, cache.Stats().Hits)
+	assert.Equal(t, keys, client.Keys("warm:"))
+}